@@ -2,30 +2,336 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/slok/brigade-exporter/pkg/config"
 )
 
+// envPrefix is the prefix of the environment variables of the flags.
+const envPrefix = "BRIGADE_EXPORTER_"
+
+// noEnvFlags are the flags that can't be set with environment variables, they are
+// actions of the command line (e.g: a BRIGADE_EXPORTER_VERSION=v0.5.0 environment
+// variable would make the exporter print the version or fail instead of running).
+var noEnvFlags = map[string]bool{
+	"version": true,
+	"help":    true,
+}
+
 // Defaults.
 const (
-	listenAddrDef  = ":9480"
-	metricsPathDef = "/metrics"
-	namespaceDef   = "default"
+	listenAddrDef       = ":9480"
+	metricsPathDef      = "/metrics"
+	probePathDef        = "/probe"
+	namespaceDef        = "default"
+	metricsNamespaceDef = "brigade"
+	flakyWindowDef      = 24 * time.Hour
+	doraWindowDef       = 30 * 24 * time.Hour
+	collectTimeoutDef   = 10 * time.Second
+	stuckThresholdDef   = time.Hour
+	configCheckDef      = 10 * time.Second
+	pushJobDef          = "brigade-exporter"
+	pushIntervalDef     = 30 * time.Second
+	rwIntervalDef       = 30 * time.Second
+	rwTimeoutDef        = 10 * time.Second
+	rwBatchSizeDef      = 500
+	rwQueueSizeDef      = 100
+	rwMaxRetriesDef     = 3
+	otlpIntervalDef     = 30 * time.Second
+	otlpTimeoutDef      = 10 * time.Second
+	apiMaxAgeDef        = 30 * time.Second
 )
 
+// durations is a flag that accepts a comma separated list of durations, apart
+// from the regular duration units it accepts days (e.g: 1h,24h,7d).
+type durations []time.Duration
+
+func (d *durations) String() string {
+	ss := make([]string, len(*d))
+	for i, dd := range *d {
+		ss[i] = dd.String()
+	}
+	return strings.Join(ss, ",")
+}
+
+func (d *durations) Set(value string) error {
+	var ds durations
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		dd, err := config.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		ds = append(ds, dd)
+	}
+
+	*d = ds
+	return nil
+}
+
+// durationMap is a flag that accepts a comma separated list of key and duration
+// pairs (e.g: jobs=30s,projects=5m).
+type durationMap map[string]time.Duration
+
+func (d *durationMap) String() string {
+	keys := make([]string, 0, len(*d))
+	for k := range *d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = fmt.Sprintf("%s=%s", k, (*d)[k])
+	}
+	return strings.Join(ss, ",")
+}
+
+func (d *durationMap) Set(value string) error {
+	dm := durationMap{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid key and duration pair %q", v)
+		}
+
+		dd, err := config.ParseDuration(kv[1])
+		if err != nil {
+			return err
+		}
+		dm[kv[0]] = dd
+	}
+
+	*d = dm
+	return nil
+}
+
+// intMap is a flag that accepts a comma separated list of key and integer
+// pairs (e.g: projects=1000,dora=500).
+type intMap map[string]int
+
+func (m *intMap) String() string {
+	keys := make([]string, 0, len(*m))
+	for k := range *m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = fmt.Sprintf("%s=%d", k, (*m)[k])
+	}
+	return strings.Join(ss, ",")
+}
+
+func (m *intMap) Set(value string) error {
+	im := intMap{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid key and integer pair %q", v)
+		}
+
+		i, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return err
+		}
+		im[kv[0]] = i
+	}
+
+	*m = im
+	return nil
+}
+
+// stringList is a flag that accepts a comma separated list of values.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	var ss stringList
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			ss = append(ss, v)
+		}
+	}
+
+	*s = ss
+	return nil
+}
+
+// stringMap is a flag that accepts a comma separated list of key and value
+// pairs (e.g: cluster=prod-1,environment=production).
+type stringMap map[string]string
+
+func (s *stringMap) String() string {
+	keys := make([]string, 0, len(*s))
+	for k := range *s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = fmt.Sprintf("%s=%s", k, (*s)[k])
+	}
+	return strings.Join(ss, ",")
+}
+
+func (s *stringMap) Set(value string) error {
+	sm := stringMap{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid key and value pair %q", v)
+		}
+		sm[kv[0]] = kv[1]
+	}
+
+	*s = sm
+	return nil
+}
+
+// stringListMap is a flag that accepts a comma separated list of key and colon
+// separated values pairs (e.g: brigade_build_info=id:project_id,brigade_job_info=id).
+type stringListMap map[string][]string
+
+func (s *stringListMap) String() string {
+	keys := make([]string, 0, len(*s))
+	for k := range *s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = fmt.Sprintf("%s=%s", k, strings.Join((*s)[k], ":"))
+	}
+	return strings.Join(ss, ",")
+}
+
+func (s *stringListMap) Set(value string) error {
+	sm := stringListMap{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid key and values pair %q", v)
+		}
+
+		values := []string{}
+		for _, vv := range strings.Split(kv[1], ":") {
+			if vv != "" {
+				values = append(values, vv)
+			}
+		}
+		sm[kv[0]] = values
+	}
+
+	*s = sm
+	return nil
+}
+
 // flags are the flags of the app
 type flags struct {
 	fs *flag.FlagSet
 
 	kubeConfig              string
+	configFile              string
+	configCheckInterval     time.Duration
 	listenAddress           string
 	metricsPath             string
+	webConfigFile           string
+	tlsCertFile             string
+	tlsKeyFile              string
+	tlsClientCAFile         string
+	probePath               string
+	probeTargets            stringList
+	enableAPI               bool
+	apiMaxAge               time.Duration
+	stuckBuildThreshold     time.Duration
+	pushgatewayURL          string
+	pushgatewayJob          string
+	pushgatewayGrouping     stringMap
+	pushgatewayInterval     time.Duration
+	pushgatewayUsername     string
+	pushgatewayPassword     string
+	remoteWriteURL          string
+	remoteWriteInterval     time.Duration
+	remoteWriteTimeout      time.Duration
+	remoteWriteBatchSize    int
+	remoteWriteQueueSize    int
+	remoteWriteMaxRetries   int
+	remoteWriteLabels       stringMap
+	remoteWriteUsername     string
+	remoteWritePassword     string
+	otlpEndpoint            string
+	otlpInterval            time.Duration
+	otlpTimeout             time.Duration
+	otlpHeaders             stringMap
+	otlpCluster             string
+	otlpResourceAttributes  stringMap
 	namespace               string
+	metricsNamespace        string
+	metricsPrefix           string
+	metricsConstLabels      stringMap
+	metricsRenameLabels     stringMap
+	metricsDropLabels       stringList
+	metricsAllowedLabels    stringListMap
+	collectTimeout          time.Duration
+	collectorTimeouts       durationMap
+	collectorIntervals      durationMap
+	collectorMaxSeries      intMap
+	disableCollectors       stringList
 	disableProjectCollector bool
+	blessedWorkerImages     stringList
+	defaultWorkerImage      string
 	disableBuildCollector   bool
+	buildsMaxSeries         int
 	disableJobCollector     bool
+	jobsMaxSeries           int
+	statusStateSet          bool
+	enableFlakyJobCollector bool
+	flakyJobWindow          time.Duration
+	enableSuccessCollector  bool
+	successRatioWindows     durations
+	enableDORACollector     bool
+	doraDeployEventTypes    stringList
+	doraWindow              time.Duration
 	development             bool
 	fake                    bool
 	debug                   bool
@@ -39,6 +345,11 @@ func newFlags() *flags {
 	}
 	f.init()
 
+	if err := f.parse(os.Args[1:], os.LookupEnv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	return f
 }
 
@@ -47,17 +358,245 @@ func (f *flags) init() {
 
 	// register flags
 	f.fs.StringVar(&f.kubeConfig, "kubeconfig", kubehome, "kubernetes configuration path, only used when development mode enabled")
+	f.fs.StringVar(&f.configFile, "config", "", "path of the YAML configuration file, reloaded when it changes or on SIGHUP, the flags override the file settings")
+	f.fs.DurationVar(&f.configCheckInterval, "config-check-interval", configCheckDef, "the interval to check if the configuration file changed, 0 disables it")
 	f.fs.StringVar(&f.listenAddress, "listen-addr", listenAddrDef, "the address the exporter will be serving the metrics")
 	f.fs.StringVar(&f.metricsPath, "metrics-path", metricsPathDef, "the path to serve the metrics")
+	f.fs.StringVar(&f.webConfigFile, "web-config-file", "", "path of the web configuration file to enable TLS and authentication, in the Prometheus exporter-toolkit format")
+	f.fs.StringVar(&f.tlsCertFile, "tls-cert-file", "", "path of the TLS certificate to serve using HTTPS, reloaded when it changes, overrides the web configuration file")
+	f.fs.StringVar(&f.tlsKeyFile, "tls-key-file", "", "path of the TLS certificate key, reloaded when it changes, overrides the web configuration file")
+	f.fs.StringVar(&f.tlsClientCAFile, "tls-client-ca-file", "", "path of the CA to verify the client certificates (mTLS), overrides the web configuration file")
+	f.fs.StringVar(&f.probePath, "probe-path", probePathDef, "the path to serve the metrics of the probe targets")
+	f.fs.Var(&f.probeTargets, "probe-targets", "comma separated Brigade installations allowed to be probed, as namespaces with an optional kubeconfig context (e.g: brigade,prod/brigade), the probe is disabled if empty")
+	f.fs.BoolVar(&f.enableAPI, "enable-api", false, "enables the read only JSON API of the brigade projects, builds and jobs")
+	f.fs.DurationVar(&f.apiMaxAge, "api-max-age", apiMaxAgeDef, "the max age of the brigade state got by the collectors that the JSON API and the status page serve, older state is requested again to brigade")
+	f.fs.DurationVar(&f.stuckBuildThreshold, "stuck-build-threshold", stuckThresholdDef, "the time after a running or pending build is shown as stuck on the status page")
+	f.fs.StringVar(&f.pushgatewayURL, "pushgateway-url", "", "URL of the Pushgateway to push the metrics periodically, the push mode is disabled if empty")
+	f.fs.StringVar(&f.pushgatewayJob, "pushgateway-job", pushJobDef, "the job of the metrics pushed to the Pushgateway")
+	f.fs.Var(&f.pushgatewayGrouping, "pushgateway-grouping", "comma separated label and value pairs of the grouping key of the metrics pushed to the Pushgateway, apart from the job (e.g: cluster=prod-1)")
+	f.fs.DurationVar(&f.pushgatewayInterval, "pushgateway-interval", pushIntervalDef, "the interval between the pushes to the Pushgateway")
+	f.fs.StringVar(&f.pushgatewayUsername, "pushgateway-username", "", "optional basic auth username of the Pushgateway")
+	f.fs.StringVar(&f.pushgatewayPassword, "pushgateway-password", "", "optional basic auth password of the Pushgateway")
+	f.fs.StringVar(&f.remoteWriteURL, "remote-write-url", "", "URL of the Prometheus remote write endpoint to send the metrics periodically, the remote write is disabled if empty")
+	f.fs.DurationVar(&f.remoteWriteInterval, "remote-write-interval", rwIntervalDef, "the interval between the gatherings of the metrics sent with remote write")
+	f.fs.DurationVar(&f.remoteWriteTimeout, "remote-write-timeout", rwTimeoutDef, "the timeout of the remote write requests")
+	f.fs.IntVar(&f.remoteWriteBatchSize, "remote-write-batch-size", rwBatchSizeDef, "max number of samples sent on every remote write request")
+	f.fs.IntVar(&f.remoteWriteQueueSize, "remote-write-queue-size", rwQueueSizeDef, "max number of batches waiting to be sent with remote write, the new batches are dropped when full")
+	f.fs.IntVar(&f.remoteWriteMaxRetries, "remote-write-max-retries", rwMaxRetriesDef, "max number of retries of the failed remote write requests, -1 disables the retries")
+	f.fs.Var(&f.remoteWriteLabels, "remote-write-external-labels", "comma separated label and value pairs added to all the series sent with remote write (e.g: cluster=edge-1)")
+	f.fs.StringVar(&f.remoteWriteUsername, "remote-write-username", "", "optional basic auth username of the remote write endpoint")
+	f.fs.StringVar(&f.remoteWritePassword, "remote-write-password", "", "optional basic auth password of the remote write endpoint")
+	f.fs.StringVar(&f.otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP metrics endpoint to export the metrics periodically (e.g: http://otel-collector:4318/v1/metrics), the OTLP export is disabled if empty")
+	f.fs.DurationVar(&f.otlpInterval, "otlp-interval", otlpIntervalDef, "the interval between the OTLP exports")
+	f.fs.DurationVar(&f.otlpTimeout, "otlp-timeout", otlpTimeoutDef, "the timeout of the OTLP export requests")
+	f.fs.Var(&f.otlpHeaders, "otlp-headers", "comma separated header and value pairs sent on the OTLP export requests (e.g: Authorization=Bearer token)")
+	f.fs.StringVar(&f.otlpCluster, "otlp-cluster", "", "optional cluster name set as the k8s.cluster.name resource attribute of the OTLP metrics")
+	f.fs.Var(&f.otlpResourceAttributes, "otlp-resource-attributes", "comma separated attribute and value pairs added to the resource of the OTLP metrics (e.g: deployment.environment=production)")
 	f.fs.StringVar(&f.namespace, "namespace", namespaceDef, "the namespace of brigade")
+	f.fs.StringVar(&f.metricsNamespace, "metrics-namespace", metricsNamespaceDef, "the namespace of the metric names")
+	f.fs.StringVar(&f.metricsPrefix, "metrics-prefix", "", "optional prefix that will be added to the metric names")
+	f.fs.Var(&f.metricsConstLabels, "metrics-const-labels", "comma separated label and value pairs that will be added to all the metrics (e.g: cluster=prod-1,environment=production)")
+	f.fs.Var(&f.metricsRenameLabels, "metrics-rename-labels", "comma separated original and new label name pairs to rename the labels of the metrics (e.g: version=commit)")
+	f.fs.Var(&f.metricsDropLabels, "metrics-drop-labels", "comma separated label names that will be dropped from the metrics (e.g: image,version)")
+	f.fs.Var(&f.metricsAllowedLabels, "metrics-allowed-labels", "comma separated metric and colon separated allowed labels pairs, the rest of the labels of the metric are dropped and the metrics aggregated (e.g: brigade_build_info=id:project_id)")
+	f.fs.DurationVar(&f.collectTimeout, "collect-timeout", collectTimeoutDef, "the default timeout of the collectors to gather the metrics")
+	f.fs.Var(&f.collectorTimeouts, "collector-timeouts", "comma separated collector and timeout pairs that override the default timeout (e.g: jobs=30s,projects=5s)")
+	f.fs.Var(&f.collectorIntervals, "collector-intervals", "comma separated collector and minimum refresh interval pairs, the collectors will serve cached metrics until the interval passes (e.g: projects=5m)")
+	f.fs.Var(&f.collectorMaxSeries, "collector-max-series", "comma separated collector and max number of series pairs, the series over the limit are dropped (e.g: projects=1000,dora=500)")
+	f.fs.Var(&f.disableCollectors, "disable-collectors", "comma separated names of the collectors to disable (e.g: jobs,dora)")
 	f.fs.BoolVar(&f.disableProjectCollector, "disable-project-collector", false, "disables the metric gathering for brigade projects")
+	f.fs.Var(&f.blessedWorkerImages, "blessed-worker-images", "comma separated worker images that the projects should use, the projects using other worker images or older versions will be outdated")
+	f.fs.StringVar(&f.defaultWorkerImage, "default-worker-image", "", "worker image of the projects without a custom worker (default brigadecore/brigade-worker:latest)")
 	f.fs.BoolVar(&f.disableBuildCollector, "disable-build-collector", false, "disables the metric gathering for brigade builds")
+	f.fs.IntVar(&f.buildsMaxSeries, "builds-max-series", 0, "max number of series of the brigade builds collector, 0 means no limit")
 	f.fs.BoolVar(&f.disableJobCollector, "disable-job-collector", false, "disables the metric gathering for brigade jobs")
+	f.fs.IntVar(&f.jobsMaxSeries, "jobs-max-series", 0, "max number of series of the brigade jobs collector, 0 means no limit")
+	f.fs.BoolVar(&f.statusStateSet, "status-state-set", false, "sends the build and job status metrics for every known status with 0 or 1 values")
+	f.fs.BoolVar(&f.enableFlakyJobCollector, "enable-flaky-job-collector", false, "enables the metric gathering for brigade flaky jobs")
+	f.fs.DurationVar(&f.flakyJobWindow, "flaky-job-window", flakyWindowDef, "the sliding window used to calculate the flakiness of the jobs")
+	f.fs.BoolVar(&f.enableSuccessCollector, "enable-build-success-ratio-collector", false, "enables the metric gathering for brigade project build success ratio")
+	f.fs.Var(&f.successRatioWindows, "build-success-ratio-windows", "comma separated sliding windows used to calculate the build success ratio (default 1h,24h,7d)")
+	f.fs.BoolVar(&f.enableDORACollector, "enable-dora-collector", false, "enables the metric gathering for DORA metrics based on the brigade deployment builds")
+	f.fs.Var(&f.doraDeployEventTypes, "dora-deploy-event-types", "comma separated build event types that count as deployments (default deploy)")
+	f.fs.DurationVar(&f.doraWindow, "dora-window", doraWindowDef, "the sliding window used to calculate the DORA metrics")
 	f.fs.BoolVar(&f.development, "development", false, "development flag will run the exporter in development mode")
 	f.fs.BoolVar(&f.fake, "fake", false, "fake flag will run the exporter faking the data from brigade")
 	f.fs.BoolVar(&f.debug, "debug", false, "enable debug mode")
 	f.fs.BoolVar(&f.version, "version", false, "show version")
 
-	// Parse flags
-	f.fs.Parse(os.Args[1:])
+	// Show the environment variable of every flag on the help.
+	f.fs.VisitAll(func(fl *flag.Flag) {
+		if !noEnvFlags[fl.Name] {
+			fl.Usage = fmt.Sprintf("%s [%s]", fl.Usage, envName(fl.Name))
+		}
+	})
+}
+
+// parse parses the command line arguments and the environment variables of the flags
+// that are not set on the command line.
+func (f *flags) parse(args []string, lookupEnv func(string) (string, bool)) error {
+	if err := f.fs.Parse(args); err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	var err error
+	f.fs.VisitAll(func(fl *flag.Flag) {
+		if set[fl.Name] || noEnvFlags[fl.Name] || err != nil {
+			return
+		}
+
+		env := envName(fl.Name)
+		if v, ok := lookupEnv(env); ok {
+			if serr := f.fs.Set(fl.Name, v); serr != nil {
+				err = fmt.Errorf("invalid value %q for %s environment variable: %s", v, env, serr)
+			}
+		}
+	})
+
+	return err
+}
+
+// envName returns the environment variable name of a flag (e.g: metrics-path will
+// be BRIGADE_EXPORTER_METRICS_PATH).
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// apply sets the flag values on the configuration, if onlySet is true only the flags
+// set on the command line are applied, otherwise all of them with their defaults.
+func (f *flags) apply(cfg *config.Config, onlySet bool) {
+	visit := f.fs.VisitAll
+	if onlySet {
+		visit = f.fs.Visit
+	}
+
+	cc := &cfg.Collectors
+	visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "metrics-namespace":
+			cfg.Metrics.Namespace = f.metricsNamespace
+		case "metrics-prefix":
+			cfg.Metrics.Prefix = f.metricsPrefix
+		case "metrics-const-labels":
+			cfg.Metrics.ConstLabels = f.metricsConstLabels
+		case "metrics-rename-labels":
+			cfg.Metrics.RenameLabels = f.metricsRenameLabels
+		case "metrics-drop-labels":
+			cfg.Metrics.DropLabels = f.metricsDropLabels
+		case "metrics-allowed-labels":
+			cfg.Metrics.AllowedLabels = f.metricsAllowedLabels
+		case "collect-timeout":
+			cc.Timeout = config.Duration(f.collectTimeout)
+		case "collector-timeouts":
+			cc.Timeouts = toDurations(f.collectorTimeouts)
+		case "collector-intervals":
+			cc.Intervals = toDurations(f.collectorIntervals)
+		case "collector-max-series":
+			cc.MaxSeries = f.collectorMaxSeries
+		case "disable-collectors":
+			cc.Disabled = f.disableCollectors
+		case "disable-project-collector":
+			cc.Projects.Disabled = f.disableProjectCollector
+		case "blessed-worker-images":
+			cc.Projects.BlessedWorkerImages = f.blessedWorkerImages
+		case "default-worker-image":
+			cc.Projects.DefaultWorkerImage = f.defaultWorkerImage
+		case "disable-build-collector":
+			cc.Builds.Disabled = f.disableBuildCollector
+		case "builds-max-series":
+			cc.Builds.MaxSeries = f.buildsMaxSeries
+		case "disable-job-collector":
+			cc.Jobs.Disabled = f.disableJobCollector
+		case "jobs-max-series":
+			cc.Jobs.MaxSeries = f.jobsMaxSeries
+		case "status-state-set":
+			cc.StatusStateSet = f.statusStateSet
+		case "enable-flaky-job-collector":
+			cc.FlakyJobs.Enabled = f.enableFlakyJobCollector
+		case "flaky-job-window":
+			cc.FlakyJobs.Window = config.Duration(f.flakyJobWindow)
+		case "enable-build-success-ratio-collector":
+			cc.BuildSuccessRatio.Enabled = f.enableSuccessCollector
+		case "build-success-ratio-windows":
+			cc.BuildSuccessRatio.Windows = nil
+			for _, w := range f.successRatioWindows {
+				cc.BuildSuccessRatio.Windows = append(cc.BuildSuccessRatio.Windows, config.Duration(w))
+			}
+		case "enable-dora-collector":
+			cc.DORA.Enabled = f.enableDORACollector
+		case "dora-deploy-event-types":
+			cc.DORA.DeployEventTypes = f.doraDeployEventTypes
+		case "dora-window":
+			cc.DORA.Window = config.Duration(f.doraWindow)
+		case "stuck-build-threshold":
+			cfg.StatusPage.StuckBuildThreshold = config.Duration(f.stuckBuildThreshold)
+		case "probe-targets":
+			cfg.Probe.Targets = f.probeTargets
+		case "namespace":
+			cfg.Brigade.Namespace = f.namespace
+		case "kubeconfig":
+			cfg.Brigade.KubeConfig = f.kubeConfig
+		case "development":
+			cfg.Brigade.Development = f.development
+		case "fake":
+			cfg.Brigade.Fake = f.fake
+		case "pushgateway-url":
+			cfg.Pushgateway.URL = f.pushgatewayURL
+		case "pushgateway-job":
+			cfg.Pushgateway.Job = f.pushgatewayJob
+		case "pushgateway-grouping":
+			cfg.Pushgateway.Grouping = f.pushgatewayGrouping
+		case "pushgateway-interval":
+			cfg.Pushgateway.Interval = config.Duration(f.pushgatewayInterval)
+		case "pushgateway-username":
+			cfg.Pushgateway.Username = f.pushgatewayUsername
+		case "pushgateway-password":
+			cfg.Pushgateway.Password = f.pushgatewayPassword
+		case "remote-write-url":
+			cfg.RemoteWrite.URL = f.remoteWriteURL
+		case "remote-write-interval":
+			cfg.RemoteWrite.Interval = config.Duration(f.remoteWriteInterval)
+		case "remote-write-timeout":
+			cfg.RemoteWrite.Timeout = config.Duration(f.remoteWriteTimeout)
+		case "remote-write-batch-size":
+			cfg.RemoteWrite.BatchSize = f.remoteWriteBatchSize
+		case "remote-write-queue-size":
+			cfg.RemoteWrite.QueueSize = f.remoteWriteQueueSize
+		case "remote-write-max-retries":
+			cfg.RemoteWrite.MaxRetries = f.remoteWriteMaxRetries
+		case "remote-write-external-labels":
+			cfg.RemoteWrite.ExternalLabels = f.remoteWriteLabels
+		case "remote-write-username":
+			cfg.RemoteWrite.Username = f.remoteWriteUsername
+		case "remote-write-password":
+			cfg.RemoteWrite.Password = f.remoteWritePassword
+		case "otlp-endpoint":
+			cfg.OTLP.Endpoint = f.otlpEndpoint
+		case "otlp-interval":
+			cfg.OTLP.Interval = config.Duration(f.otlpInterval)
+		case "otlp-timeout":
+			cfg.OTLP.Timeout = config.Duration(f.otlpTimeout)
+		case "otlp-headers":
+			cfg.OTLP.Headers = f.otlpHeaders
+		case "otlp-cluster":
+			cfg.OTLP.Cluster = f.otlpCluster
+		case "otlp-resource-attributes":
+			cfg.OTLP.ResourceAttributes = f.otlpResourceAttributes
+		}
+	})
+}
+
+// toDurations returns the durations as configuration durations.
+func toDurations(m map[string]time.Duration) map[string]config.Duration {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]config.Duration, len(m))
+	for k, v := range m {
+		res[k] = config.Duration(v)
+	}
+	return res
 }
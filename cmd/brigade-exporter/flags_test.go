@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/config"
+)
+
+func newTestFlags(args []string, env map[string]string) (*flags, error) {
+	f := &flags{
+		fs: flag.NewFlagSet("brigade-exporter", flag.ContinueOnError),
+	}
+	f.fs.SetOutput(ioutil.Discard)
+	f.init()
+
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	return f, f.parse(args, lookupEnv)
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		configFile string
+		expCfg     func() config.Config
+		expErr     bool
+	}{
+		{
+			name: "Without flags, environment variables or configuration file the defaults should be used.",
+			expCfg: func() config.Config {
+				cfg := config.Config{}
+				cfg.Metrics.Namespace = metricsNamespaceDef
+				cfg.Collectors.Timeout = config.Duration(collectTimeoutDef)
+				return cfg
+			},
+		},
+		{
+			name:       "The configuration file should override the defaults.",
+			configFile: "metrics:\n  prefix: file_\ncollectors:\n  timeout: 30s\n  builds:\n    max_series: 100\n",
+			expCfg: func() config.Config {
+				cfg := config.Config{}
+				cfg.Metrics.Namespace = metricsNamespaceDef
+				cfg.Metrics.Prefix = "file_"
+				cfg.Collectors.Timeout = config.Duration(30 * time.Second)
+				cfg.Collectors.Builds.MaxSeries = 100
+				return cfg
+			},
+		},
+		{
+			name:       "The environment variables should override the configuration file.",
+			configFile: "metrics:\n  prefix: file_\ncollectors:\n  timeout: 30s\n  builds:\n    max_series: 100\n",
+			env: map[string]string{
+				"BRIGADE_EXPORTER_METRICS_PREFIX":     "env_",
+				"BRIGADE_EXPORTER_BUILDS_MAX_SERIES":  "200",
+				"BRIGADE_EXPORTER_DISABLE_COLLECTORS": "dora,jobs",
+			},
+			expCfg: func() config.Config {
+				cfg := config.Config{}
+				cfg.Metrics.Namespace = metricsNamespaceDef
+				cfg.Metrics.Prefix = "env_"
+				cfg.Collectors.Timeout = config.Duration(30 * time.Second)
+				cfg.Collectors.Builds.MaxSeries = 200
+				cfg.Collectors.Disabled = []string{"dora", "jobs"}
+				return cfg
+			},
+		},
+		{
+			name:       "The flags should override the environment variables and the configuration file.",
+			configFile: "metrics:\n  prefix: file_\ncollectors:\n  timeout: 30s\n  builds:\n    max_series: 100\n",
+			env: map[string]string{
+				"BRIGADE_EXPORTER_METRICS_PREFIX":    "env_",
+				"BRIGADE_EXPORTER_BUILDS_MAX_SERIES": "200",
+			},
+			args: []string{"--metrics-prefix=flag_", "--collect-timeout=1m"},
+			expCfg: func() config.Config {
+				cfg := config.Config{}
+				cfg.Metrics.Namespace = metricsNamespaceDef
+				cfg.Metrics.Prefix = "flag_"
+				cfg.Collectors.Timeout = config.Duration(time.Minute)
+				cfg.Collectors.Builds.MaxSeries = 200
+				return cfg
+			},
+		},
+		{
+			name:       "The Brigade and push backend settings should be configured like the rest.",
+			configFile: "brigade:\n  development: true\notlp:\n  endpoint: http://otel-collector:4318/v1/metrics\n  cluster: file\n",
+			env:        map[string]string{"BRIGADE_EXPORTER_OTLP_CLUSTER": "edge-1"},
+			expCfg: func() config.Config {
+				cfg := config.Config{}
+				cfg.Metrics.Namespace = metricsNamespaceDef
+				cfg.Collectors.Timeout = config.Duration(collectTimeoutDef)
+				cfg.Brigade.Development = true
+				cfg.OTLP.Endpoint = "http://otel-collector:4318/v1/metrics"
+				cfg.OTLP.Cluster = "edge-1"
+				return cfg
+			},
+		},
+		{
+			name:   "Invalid environment variable values should error.",
+			env:    map[string]string{"BRIGADE_EXPORTER_BUILDS_MAX_SERIES": "lots"},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			args := test.args
+			if test.configFile != "" {
+				dir, err := ioutil.TempDir("", "brigade-exporter")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				path := filepath.Join(dir, "config.yml")
+				if err := ioutil.WriteFile(path, []byte(test.configFile), 0600); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"--config", path}, args...)
+			}
+
+			f, err := newTestFlags(args, test.env)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			m := &Main{flags: f}
+			cfg, err := m.loadConfig()
+			if assert.NoError(err) {
+				exp := test.expCfg()
+				// The rest of the flag defaults.
+				exp.Collectors.FlakyJobs.Window = config.Duration(flakyWindowDef)
+				exp.Collectors.DORA.Window = config.Duration(doraWindowDef)
+				exp.StatusPage.StuckBuildThreshold = config.Duration(stuckThresholdDef)
+				exp.Brigade.Namespace = namespaceDef
+				exp.Brigade.KubeConfig = f.fs.Lookup("kubeconfig").DefValue
+				exp.Pushgateway.Job = pushJobDef
+				exp.Pushgateway.Interval = config.Duration(pushIntervalDef)
+				exp.RemoteWrite.Interval = config.Duration(rwIntervalDef)
+				exp.RemoteWrite.Timeout = config.Duration(rwTimeoutDef)
+				exp.RemoteWrite.BatchSize = rwBatchSizeDef
+				exp.RemoteWrite.QueueSize = rwQueueSizeDef
+				exp.RemoteWrite.MaxRetries = rwMaxRetriesDef
+				exp.OTLP.Interval = config.Duration(otlpIntervalDef)
+				exp.OTLP.Timeout = config.Duration(otlpTimeoutDef)
+				assert.Equal(exp, cfg)
+			}
+		})
+	}
+}
+
+func TestFlagsEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	env := map[string]string{
+		"BRIGADE_EXPORTER_LISTEN_ADDR": ":8080",
+		"BRIGADE_EXPORTER_FAKE":        "true",
+		"BRIGADE_EXPORTER_NAMESPACE":   "brigade",
+	}
+	f, err := newTestFlags([]string{"--namespace", "ci"}, env)
+	if assert.NoError(err) {
+		assert.Equal(":8080", f.listenAddress)
+		assert.True(f.fake)
+		assert.Equal("ci", f.namespace)
+		assert.Equal(metricsPathDef, f.metricsPath)
+	}
+}
+
+func TestFlagsEnvIgnoresVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	// The version environment variable of the deployments (e.g: the image version)
+	// shouldn't be taken as the version flag.
+	for _, v := range []string{"v0.5.0", "true"} {
+		f, err := newTestFlags(nil, map[string]string{"BRIGADE_EXPORTER_VERSION": v})
+		if assert.NoError(err) {
+			assert.False(f.version)
+		}
+	}
+}
+
+func TestFlagsHelpEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := newTestFlags(nil, nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	var b bytes.Buffer
+	f.fs.SetOutput(&b)
+	f.fs.PrintDefaults()
+
+	assert.Contains(b.String(), "the path to serve the metrics [BRIGADE_EXPORTER_METRICS_PATH]")
+	assert.Contains(b.String(), "enables the metric gathering for DORA metrics based on the brigade deployment builds [BRIGADE_EXPORTER_ENABLE_DORA_COLLECTOR]")
+	f.fs.VisitAll(func(fl *flag.Flag) {
+		if fl.Name == "version" {
+			assert.NotContains(b.String(), envName(fl.Name))
+			return
+		}
+		assert.Contains(b.String(), envName(fl.Name))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -18,9 +19,19 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/slok/brigade-exporter/pkg/api"
 	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/config"
+	"github.com/slok/brigade-exporter/pkg/gather"
+	"github.com/slok/brigade-exporter/pkg/health"
 	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/otlp"
+	"github.com/slok/brigade-exporter/pkg/probe"
+	"github.com/slok/brigade-exporter/pkg/pushgateway"
+	"github.com/slok/brigade-exporter/pkg/remotewrite"
 	"github.com/slok/brigade-exporter/pkg/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/status"
+	"github.com/slok/brigade-exporter/pkg/web"
 )
 
 const (
@@ -28,6 +39,8 @@ const (
 	versionFMT   = "brigade-exporter %s"
 	kubeCliQPS   = 100
 	kubeCliBurst = 100
+	healthzPath  = "/healthz"
+	readyPath    = "/ready"
 )
 
 var (
@@ -49,8 +62,18 @@ func (m *Main) Run() error {
 		return nil
 	}
 
+	// The configuration at start, the Brigade and push backend settings are only
+	// applied on start.
+	startCfg, err := m.loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := startCfg.Validate(); err != nil {
+		return err
+	}
+
 	// If not development json logger.
-	m.logger = log.Base(!m.flags.development)
+	m.logger = log.Base(!startCfg.Brigade.Development)
 
 	if m.flags.debug {
 		m.logger.Set("debug")
@@ -74,25 +97,74 @@ func (m *Main) Run() error {
 
 	// Exporter.
 	{
+		promReg := prometheus.NewRegistry()
+
+		// The exporter metrics outside the collectors are created once, they are
+		// named with the metrics namespace and prefix of the configuration at start.
+		namespace := startCfg.ExporterConfig().Metrics.FullNamespace()
+
 		// Prepare Services.
-		brigadeSVC, err := m.createBrigadeService()
+		brigadeSVC, err := m.createBrigadeService(startCfg.Brigade)
 		if err != nil {
 			return err
 		}
+		brigadeSVC, err = brigade.NewInstrumented(namespace, promReg, brigadeSVC)
+		if err != nil {
+			return err
+		}
+		// The API is served with the Brigade state the collectors got.
+		snapshot := brigade.NewSnapshot(brigadeSVC, m.flags.apiMaxAge)
 
-		// Prepare exporter.
-		cfg := collector.Config{
-			DisableProjects: m.flags.disableProjectCollector,
-			DisableBuilds:   m.flags.disableBuildCollector,
-			DisableJobs:     m.flags.disableJobCollector,
+		webCfg, err := m.loadWebConfig()
+		if err != nil {
+			return err
 		}
-		clr := collector.NewExporter(cfg, brigadeSVC, m.logger)
-		promReg := prometheus.NewRegistry()
-		promReg.MustRegister(clr)
-		s := m.createHTTPServer(promReg)
+
+		// Prepare the exporter, it's reloaded on every configuration reload.
+		exporter := &reloadableExporter{}
+		apply := func(cfg config.Config) error {
+			cfg, changed := cfg.KeepRestartSettings(startCfg)
+			for _, name := range changed {
+				m.logger.Warnf("%s settings changed, they will be applied on the next restart", name)
+			}
+			m.applyConfig(cfg, promReg, snapshot, exporter)
+			return nil
+		}
+		reloader, err := config.NewReloader(namespace, promReg, m.loadConfig, apply, m.logger)
+		if err != nil {
+			return err
+		}
+		if err := reloader.Reload(); err != nil {
+			return err
+		}
+		promReg.MustRegister(
+			exporter,
+			prometheus.NewProcessCollector(os.Getpid(), ""),
+			prometheus.NewGoCollector(),
+		)
+
+		// The loops of the exporter beat so the health check knows if they stalled.
+		checker := health.NewChecker(0)
+
+		s, err := m.createHTTPServer(exporter, checker, webCfg)
+		if err != nil {
+			return err
+		}
+
+		// Make the first collection so the exporter gets ready without waiting for the first scrape.
+		go func() {
+			if _, err := promReg.Gather(); err != nil {
+				m.logger.Errorf("error on the first collection: %s", err)
+			}
+		}()
 
 		g.Add(
 			func() error {
+				if webCfg.TLSEnabled() {
+					m.logger.Infof("listening on %s using TLS", m.flags.listenAddress)
+					// The certificates are loaded by the TLS configuration.
+					return s.ListenAndServeTLS("", "")
+				}
 				m.logger.Infof("listening on %s", m.flags.listenAddress)
 				return s.ListenAndServe()
 			},
@@ -105,32 +177,215 @@ func (m *Main) Run() error {
 				}
 			},
 		)
+
+		// The push backends share the gatherings of the registry.
+		pushGatherer := gather.NewShared(promReg, pushGatherMaxAge(startCfg))
+
+		// Push mode.
+		if pgCfg := startCfg.Pushgateway; pgCfg.URL != "" {
+			interval := time.Duration(pgCfg.Interval)
+			pusher, err := pushgateway.NewPusher(pushgateway.Config{
+				URL:       pgCfg.URL,
+				Job:       pgCfg.Job,
+				Grouping:  pgCfg.Grouping,
+				Interval:  interval,
+				Username:  pgCfg.Username,
+				Password:  pgCfg.Password,
+				Namespace: namespace,
+				Heartbeat: checker.Register("pushgateway", interval),
+			}, pushGatherer, promReg, m.logger)
+			if err != nil {
+				return err
+			}
+			stop := make(chan struct{})
+			g.Add(
+				func() error {
+					m.logger.Infof("pushing metrics to %s every %s", pgCfg.URL, interval)
+					pusher.Run(stop)
+					return nil
+				},
+				func(error) {
+					close(stop)
+				},
+			)
+		}
+
+		// Remote write mode.
+		if rwCfg := startCfg.RemoteWrite; rwCfg.URL != "" {
+			interval, timeout := time.Duration(rwCfg.Interval), time.Duration(rwCfg.Timeout)
+			writer, err := remotewrite.NewWriter(remotewrite.Config{
+				URL:            rwCfg.URL,
+				Interval:       interval,
+				Timeout:        timeout,
+				BatchSize:      rwCfg.BatchSize,
+				QueueSize:      rwCfg.QueueSize,
+				MaxRetries:     rwCfg.MaxRetries,
+				ExternalLabels: rwCfg.ExternalLabels,
+				Username:       rwCfg.Username,
+				Password:       rwCfg.Password,
+				Namespace:      namespace,
+				Heartbeat:      checker.Register("remote_write", interval),
+				// A batch can take a request timeout on every retry.
+				SenderHeartbeat: checker.Register("remote_write_sender", maxDuration(
+					interval,
+					time.Duration(rwCfg.MaxRetries+1)*timeout,
+				)),
+			}, pushGatherer, promReg, m.logger)
+			if err != nil {
+				return err
+			}
+			stop := make(chan struct{})
+			g.Add(
+				func() error {
+					m.logger.Infof("sending metrics with remote write to %s every %s", rwCfg.URL, interval)
+					writer.Run(stop)
+					return nil
+				},
+				func(error) {
+					close(stop)
+				},
+			)
+		}
+
+		// OTLP export.
+		if otlpCfg := startCfg.OTLP; otlpCfg.Endpoint != "" {
+			interval, timeout := time.Duration(otlpCfg.Interval), time.Duration(otlpCfg.Timeout)
+			otlpExporter, err := otlp.NewExporter(otlp.Config{
+				Endpoint:           otlpCfg.Endpoint,
+				Interval:           interval,
+				Timeout:            timeout,
+				Headers:            otlpCfg.Headers,
+				ResourceAttributes: otlpResourceAttributes(startCfg),
+				Version:            Version,
+				Namespace:          namespace,
+				Heartbeat:          checker.Register("otlp", maxDuration(interval, timeout)),
+			}, pushGatherer, promReg, m.logger)
+			if err != nil {
+				return err
+			}
+			stop := make(chan struct{})
+			g.Add(
+				func() error {
+					m.logger.Infof("exporting metrics with OTLP to %s every %s", otlpCfg.Endpoint, interval)
+					otlpExporter.Run(stop)
+					return nil
+				},
+				func(error) {
+					close(stop)
+				},
+			)
+		}
+
+		// Configuration reload on SIGHUP and when the file changes.
+		if m.flags.configFile != "" {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, syscall.SIGHUP)
+			stop := make(chan struct{})
+			g.Add(
+				func() error {
+					for {
+						select {
+						case <-c:
+							m.logger.Infof("SIGHUP received, reloading configuration")
+							reloader.Reload()
+						case <-stop:
+							return nil
+						}
+					}
+				},
+				func(error) {
+					close(stop)
+				},
+			)
+
+			if m.flags.configCheckInterval > 0 {
+				heartbeat := checker.Register("config_watcher", m.flags.configCheckInterval)
+				stopWatch := make(chan struct{})
+				g.Add(
+					func() error {
+						reloader.Watch(m.flags.configFile, m.flags.configCheckInterval, heartbeat, stopWatch)
+						return nil
+					},
+					func(error) {
+						close(stopWatch)
+					},
+				)
+			}
+		}
 	}
 
 	return g.Run()
 }
 
-// createBrigadeService will create the proper brigade service based on the required flags.
-func (m *Main) createBrigadeService() (brigade.Interface, error) {
-	if m.flags.fake {
+// createBrigadeService will create the proper brigade service based on the configuration.
+func (m *Main) createBrigadeService(cfg config.BrigadeConfig) (brigade.Interface, error) {
+	if cfg.Fake {
 		m.logger.Warnf("exporter running in faked mode")
 		return brigade.NewFake(), nil
 	}
 
-	k8scli, err := m.createKubernetesClient()
+	k8scli, err := m.createKubernetesClient(cfg)
 	if err != nil {
 		return nil, err
 	}
-	brigadeCli := azurebrigade.New(k8scli, m.flags.namespace)
+	brigadeCli := azurebrigade.New(k8scli, cfg.Namespace)
 	return brigade.New(brigadeCli, m.logger), nil
 }
 
-// loadKubernetesConfig loads kubernetes configuration based on flags.
-func (m *Main) loadKubernetesConfig() (*rest.Config, error) {
+// createTargetBrigadeService will create the brigade service of a probe target, the
+// targets are Brigade namespaces with an optional kubeconfig context (e.g: prod/brigade).
+func (m *Main) createTargetBrigadeService(cfg config.BrigadeConfig, target string) (brigade.Interface, error) {
+	if cfg.Fake {
+		return brigade.NewFake(), nil
+	}
+
+	kubeContext, namespace := "", target
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		kubeContext, namespace = target[:i], target[i+1:]
+	}
+
+	var k8sCfg *rest.Config
+	var err error
+	if kubeContext == "" {
+		k8sCfg, err = m.loadKubernetesConfig(cfg)
+	} else {
+		k8sCfg, err = m.loadKubernetesContextConfig(cfg, kubeContext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k8scli, err := kubernetes.NewForConfig(k8sCfg)
+	if err != nil {
+		return nil, err
+	}
+	brigadeCli := azurebrigade.New(k8scli, namespace)
+	return brigade.New(brigadeCli, m.logger.With("target", target)), nil
+}
+
+// loadKubernetesContextConfig loads the kubernetes configuration of a kubeconfig context.
+func (m *Main) loadKubernetesContextConfig(brigadeCfg config.BrigadeConfig, kubeContext string) (*rest.Config, error) {
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: brigadeCfg.KubeConfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load %s context configuration: %s", kubeContext, err)
+	}
+
+	// Set better cli rate limiter.
+	cfg.QPS = kubeCliQPS
+	cfg.Burst = kubeCliBurst
+
+	return cfg, nil
+}
+
+// loadKubernetesConfig loads kubernetes configuration based on the Brigade configuration.
+func (m *Main) loadKubernetesConfig(brigadeCfg config.BrigadeConfig) (*rest.Config, error) {
 	var cfg *rest.Config
 	// If devel mode then use configuration flag path.
-	if m.flags.development {
-		config, err := clientcmd.BuildConfigFromFlags("", m.flags.kubeConfig)
+	if brigadeCfg.Development {
+		config, err := clientcmd.BuildConfigFromFlags("", brigadeCfg.KubeConfig)
 		if err != nil {
 			return nil, fmt.Errorf("could not load configuration: %s", err)
 		}
@@ -151,8 +406,8 @@ func (m *Main) loadKubernetesConfig() (*rest.Config, error) {
 }
 
 // createKubernetesClient will create the proper kubernetes client.
-func (m *Main) createKubernetesClient() (kubernetes.Interface, error) {
-	config, err := m.loadKubernetesConfig()
+func (m *Main) createKubernetesClient(brigadeCfg config.BrigadeConfig) (kubernetes.Interface, error) {
+	config, err := m.loadKubernetesConfig(brigadeCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -160,25 +415,221 @@ func (m *Main) createKubernetesClient() (kubernetes.Interface, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-// createHTTPServer creates the http server that serves prometheus metrics.
-func (m *Main) createHTTPServer(promReg *prometheus.Registry) http.Server {
-	h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
+// loadWebConfig loads the web configuration file and applies the TLS flags on top of it.
+func (m *Main) loadWebConfig() (web.Config, error) {
+	var cfg web.Config
+	if m.flags.webConfigFile != "" {
+		c, err := web.LoadConfig(m.flags.webConfigFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg = c
+	}
+
+	if m.flags.tlsCertFile != "" {
+		cfg.TLSServerConfig.CertFile = m.flags.tlsCertFile
+	}
+	if m.flags.tlsKeyFile != "" {
+		cfg.TLSServerConfig.KeyFile = m.flags.tlsKeyFile
+	}
+	if m.flags.tlsClientCAFile != "" {
+		cfg.TLSServerConfig.ClientCAFile = m.flags.tlsClientCAFile
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// otlpResourceAttributes returns the resource attributes of the OTLP metrics, the
+// configured attributes override the default ones.
+func otlpResourceAttributes(cfg config.Config) map[string]string {
+	attrs := map[string]string{
+		otlp.ServiceNameKey:    "brigade-exporter",
+		otlp.ServiceVersionKey: Version,
+		otlp.NamespaceKey:      cfg.Brigade.Namespace,
+	}
+	if cfg.OTLP.Cluster != "" {
+		attrs[otlp.ClusterKey] = cfg.OTLP.Cluster
+	}
+	for k, v := range cfg.OTLP.ResourceAttributes {
+		attrs[k] = v
+	}
+
+	return attrs
+}
+
+// pushGatherMaxAge returns the max age of the gatherings shared by the push backends,
+// half the shortest interval so every backend gathers once per interval at most.
+func pushGatherMaxAge(cfg config.Config) time.Duration {
+	var min time.Duration
+	for _, b := range []struct {
+		enabled  bool
+		interval time.Duration
+	}{
+		{enabled: cfg.Pushgateway.URL != "", interval: time.Duration(cfg.Pushgateway.Interval)},
+		{enabled: cfg.RemoteWrite.URL != "", interval: time.Duration(cfg.RemoteWrite.Interval)},
+		{enabled: cfg.OTLP.Endpoint != "", interval: time.Duration(cfg.OTLP.Interval)},
+	} {
+		if b.enabled && b.interval > 0 && (min == 0 || b.interval < min) {
+			min = b.interval
+		}
+	}
+
+	return min / 2
+}
+
+// maxDuration returns the longest duration.
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// loadConfig loads the configuration, the configuration file settings override the
+// flag defaults and the flags set on the command line override the file settings.
+func (m *Main) loadConfig() (config.Config, error) {
+	cfg := config.Config{}
+	m.flags.apply(&cfg, false)
+	if m.flags.configFile == "" {
+		return cfg, nil
+	}
+
+	cfg, err := config.Load(m.flags.configFile, cfg)
+	if err != nil {
+		return cfg, err
+	}
+	m.flags.apply(&cfg, true)
+
+	return cfg, nil
+}
+
+// applyConfig creates the exporter and the handlers that depend on the configuration
+// and replaces the current ones. If the configuration didn't change nothing is replaced,
+// otherwise the current exporter is reloaded so it keeps its state.
+func (m *Main) applyConfig(cfg config.Config, promReg *prometheus.Registry, brigadeSVC *brigade.Snapshot, exporter *reloadableExporter) {
+	if exporter.unchanged(cfg) {
+		m.logger.Debugf("configuration unchanged, keeping the current exporter")
+		return
+	}
+
+	exporterCfg := cfg.ExporterConfig()
+	exporterCfg.Version = Version
+	clr, _ := exporter.current()
+	if clr == nil {
+		clr = collector.NewExporter(exporterCfg, brigadeSVC, m.logger)
+	} else {
+		clr = clr.Reload(exporterCfg, brigadeSVC, m.logger)
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle(m.flags.metricsPath, h)
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>Brigade Exporter</title></head>
-			<body>
-			<h1>Brigade Exporter</h1>
-			<p><a href="` + m.flags.metricsPath + `">Metrics</a></p>
-			</body>
-			</html>`))
+	mux.Handle(m.flags.metricsPath, &metricsHandler{
+		unfiltered: promhttp.HandlerFor(promReg, promhttp.HandlerOpts{}),
+		exporter:   clr,
+		logger:     m.logger,
 	})
 
-	return http.Server{
+	statusCfg := status.Config{
+		MetricsPath:    m.flags.metricsPath,
+		StuckThreshold: time.Duration(cfg.StatusPage.StuckBuildThreshold),
+	}
+	mux.Handle("/", status.NewHandler(statusCfg, brigadeSVC.Cached(), clr, m.logger.With("handler", "status")))
+
+	// Probe of other Brigade installations, only if there are allowed targets. The
+	// probe is reloaded so the exporters of the targets keep their state.
+	var prober *probe.Handler
+	if len(cfg.Probe.Targets) > 0 {
+		probeCfg := probe.Config{
+			Targets:  cfg.Probe.Targets,
+			Exporter: exporterCfg,
+		}
+		if prober = exporter.currentProber(); prober == nil {
+			createTarget := func(target string) (brigade.Interface, error) {
+				return m.createTargetBrigadeService(cfg.Brigade, target)
+			}
+			prober = probe.NewHandler(probeCfg, createTarget, m.logger.With("handler", "probe"))
+		} else {
+			prober = prober.Reload(probeCfg)
+		}
+		mux.Handle(m.flags.probePath, prober)
+	}
+
+	// JSON API of the Brigade state.
+	if m.flags.enableAPI {
+		mux.Handle(api.Prefix, api.NewHandler(brigadeSVC.Cached(), m.logger.With("handler", "api")))
+	}
+
+	exporter.set(cfg, clr, prober, mux)
+}
+
+// createHTTPServer creates the http server that serves the current handlers of the
+// exporter and the health checks.
+func (m *Main) createHTTPServer(exporter *reloadableExporter, checker *health.Checker, webCfg web.Config) (*http.Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/", web.NewAuthHandler(webCfg, exporter))
+
+	// Health checks, the process is alive if the server is serving and none of the
+	// loops has stalled. They don't require authentication so the orchestrator probes
+	// can reach them.
+	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
+		if err := checker.Check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc(readyPath, func(w http.ResponseWriter, r *http.Request) {
+		if !exporter.Ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	s := &http.Server{
 		Handler: mux,
 		Addr:    m.flags.listenAddress,
 	}
+
+	if webCfg.TLSEnabled() {
+		tc, err := web.NewTLSConfig(webCfg.TLSServerConfig, m.logger)
+		if err != nil {
+			return nil, err
+		}
+		s.TLSConfig = tc
+	}
+
+	return s, nil
+}
+
+// metricsHandler serves the metrics of all the collectors or only the ones
+// selected with the collect[] query parameter (e.g: /metrics?collect[]=builds&collect[]=jobs).
+type metricsHandler struct {
+	unfiltered http.Handler
+	exporter   *collector.Exporter
+	logger     log.Logger
+}
+
+// ServeHTTP satisfies http.Handler.
+func (h *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	names := r.URL.Query()["collect[]"]
+	if len(names) == 0 {
+		h.unfiltered.ServeHTTP(w, r)
+		return
+	}
+
+	// Only the filtered exporter metrics are served, the filtered exporter
+	// shares the service and the caches with the unfiltered one.
+	exporter, err := h.exporter.Filtered(names)
+	if err != nil {
+		h.logger.Warnf("couldn't create filtered exporter: %s", err)
+		http.Error(w, fmt.Sprintf("couldn't create filtered exporter: %s", err), http.StatusBadRequest)
+		return
+	}
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(exporter); err != nil {
+		http.Error(w, fmt.Sprintf("couldn't register filtered exporter: %s", err), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
 }
 
 // printVersion prints the version of the app.
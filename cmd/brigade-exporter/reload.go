@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/config"
+	"github.com/slok/brigade-exporter/pkg/probe"
+)
+
+// reloadableExporter is a prometheus collector that collects the current exporter and
+// an http.Handler that serves the current handlers. The exporter and the handlers that
+// depend on the configuration are replaced on every configuration reload that changes
+// the configuration.
+type reloadableExporter struct {
+	mu       sync.RWMutex
+	cfg      *config.Config
+	exporter *collector.Exporter
+	prober   *probe.Handler
+	handler  http.Handler
+	wasReady bool
+}
+
+// unchanged returns true if the configuration is the one already applied.
+func (r *reloadableExporter) unchanged(cfg config.Config) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cfg != nil && reflect.DeepEqual(*r.cfg, cfg)
+}
+
+// set replaces the current configuration, exporter, probe handler (nil if the probe is
+// disabled) and handler.
+func (r *reloadableExporter) set(cfg config.Config, exporter *collector.Exporter, prober *probe.Handler, handler http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Once ready we don't want to stop being ready because of a reload.
+	if r.exporter != nil && r.exporter.Ready() {
+		r.wasReady = true
+	}
+	r.cfg = &cfg
+	r.exporter = exporter
+	r.prober = prober
+	r.handler = handler
+}
+
+func (r *reloadableExporter) current() (*collector.Exporter, http.Handler) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.exporter, r.handler
+}
+
+func (r *reloadableExporter) currentProber() *probe.Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.prober
+}
+
+// Describe satisfies prometheus.Collector interface. It's an unchecked collector
+// because the metric descriptions can change on every reload.
+func (r *reloadableExporter) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect satisfies prometheus.Collector interface.
+func (r *reloadableExporter) Collect(ch chan<- prometheus.Metric) {
+	exporter, _ := r.current()
+	exporter.Collect(ch)
+}
+
+// Ready returns if any of the exporters has been ready.
+func (r *reloadableExporter) Ready() bool {
+	exporter, _ := r.current()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.wasReady || exporter.Ready()
+}
+
+// ServeHTTP satisfies http.Handler.
+func (r *reloadableExporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, handler := r.current()
+	handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	// Prefix is the path prefix of the API.
+	Prefix = "/api/v1/"
+
+	limitDef = 100
+	limitMax = 1000
+)
+
+// Page is a page of the items of a list.
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// build is a build of the API, the duration is in seconds.
+type build struct {
+	*brigade.Build
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// job is a job of the API, the duration is in seconds.
+type job struct {
+	*brigade.Job
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// errorResponse is the body of the failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handler serves the read only JSON API of the Brigade state.
+type handler struct {
+	brigadeSVC brigade.Interface
+	logger     log.Logger
+}
+
+// NewHandler returns a new handler that serves the Brigade projects, builds
+// and jobs as JSON. Use the cached reads of the Brigade service snapshot the
+// collectors use (brigade.Snapshot.Cached) so the requests are served with the
+// state the collectors got instead of making requests to Brigade. The handler
+// expects the requests with the whole path (e.g: /api/v1/projects).
+//
+// - /api/v1/projects
+// - /api/v1/builds?project=<project-id>&status=<status>
+// - /api/v1/builds/<build-id>/jobs?status=<status>
+//
+// All the lists accept limit and offset parameters to paginate the results.
+func NewHandler(brigadeSVC brigade.Interface, logger log.Logger) http.Handler {
+	return &handler{
+		brigadeSVC: brigadeSVC,
+		logger:     logger,
+	}
+}
+
+// ServeHTTP satisfies http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	limit, offset, err := pagination(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, Prefix), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "projects":
+		h.listProjects(w, limit, offset)
+	case len(parts) == 1 && parts[0] == "builds":
+		h.listBuilds(w, r.URL.Query().Get("project"), r.URL.Query().Get("status"), limit, offset)
+	case len(parts) == 3 && parts[0] == "builds" && parts[1] != "" && parts[2] == "jobs":
+		h.listJobs(w, parts[1], r.URL.Query().Get("status"), limit, offset)
+	default:
+		h.writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+	}
+}
+
+// listProjects writes the projects sorted by name.
+func (h *handler) listProjects(w http.ResponseWriter, limit, offset int) {
+	prjs, err := h.brigadeSVC.GetProjects()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Don't sort the slice of the service.
+	prjs = append([]*brigade.Project{}, prjs...)
+	sort.SliceStable(prjs, func(i, j int) bool {
+		if prjs[i].Name != prjs[j].Name {
+			return prjs[i].Name < prjs[j].Name
+		}
+		return prjs[i].ID < prjs[j].ID
+	})
+
+	total := len(prjs)
+	start, end := bounds(total, limit, offset)
+	h.writePage(w, prjs[start:end], total, limit, offset)
+}
+
+// listBuilds writes the builds of the project and with the status (if set) sorted
+// by the most recently created ones first.
+func (h *handler) listBuilds(w http.ResponseWriter, projectID, status string, limit, offset int) {
+	blds, err := h.brigadeSVC.GetBuilds()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filtered := []*brigade.Build{}
+	for _, b := range blds {
+		if projectID != "" && b.ProjectID != projectID {
+			continue
+		}
+		if status != "" && !strings.EqualFold(b.Status, status) {
+			continue
+		}
+		filtered = append(filtered, b)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if ci, cj := filtered[i].Created(), filtered[j].Created(); !ci.Equal(cj) {
+			return ci.After(cj)
+		}
+		return filtered[i].ID < filtered[j].ID
+	})
+
+	total := len(filtered)
+	start, end := bounds(total, limit, offset)
+	items := make([]build, 0, end-start)
+	for _, b := range filtered[start:end] {
+		items = append(items, build{Build: b, DurationSeconds: b.Duration.Seconds()})
+	}
+	h.writePage(w, items, total, limit, offset)
+}
+
+// listJobs writes the jobs of the build and with the status (if set) sorted
+// by creation.
+func (h *handler) listJobs(w http.ResponseWriter, buildID, status string, limit, offset int) {
+	blds, err := h.brigadeSVC.GetBuilds()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	found := false
+	for _, b := range blds {
+		if b.ID == buildID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		h.writeError(w, http.StatusNotFound, fmt.Errorf("build %s not found", buildID))
+		return
+	}
+
+	jobs, err := h.brigadeSVC.GetJobs()
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filtered := []*brigade.Job{}
+	for _, j := range jobs {
+		if j.BuildID != buildID {
+			continue
+		}
+		if status != "" && !strings.EqualFold(j.Status, status) {
+			continue
+		}
+		filtered = append(filtered, j)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if !filtered[i].Creation.Equal(filtered[j].Creation) {
+			return filtered[i].Creation.Before(filtered[j].Creation)
+		}
+		return filtered[i].ID < filtered[j].ID
+	})
+
+	total := len(filtered)
+	start, end := bounds(total, limit, offset)
+	items := make([]job, 0, end-start)
+	for _, j := range filtered[start:end] {
+		items = append(items, job{Job: j, DurationSeconds: j.Duration.Seconds()})
+	}
+	h.writePage(w, items, total, limit, offset)
+}
+
+func (h *handler) writePage(w http.ResponseWriter, items interface{}, total, limit, offset int) {
+	h.writeJSON(w, http.StatusOK, Page{
+		Items:  items,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (h *handler) writeError(w http.ResponseWriter, code int, err error) {
+	if code >= http.StatusInternalServerError {
+		h.logger.Errorf("api request failed: %s", err)
+	}
+	h.writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Errorf("error encoding api response: %s", err)
+	}
+}
+
+// pagination returns the limit and the offset of the request.
+func pagination(r *http.Request) (limit, offset int, err error) {
+	limit, offset = limitDef, 0
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > limitMax {
+			return 0, 0, fmt.Errorf("invalid limit %q, it should be between 1 and %d", v, limitMax)
+		}
+	}
+
+	if v := q.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// bounds returns the start and end indexes of the page.
+func bounds(total, limit, offset int) (start, end int) {
+	start = offset
+	if start > total {
+		start = total
+	}
+	end = start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/api"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+var (
+	t1 = time.Date(2019, 1, 10, 12, 0, 0, 0, time.UTC)
+	t2 = t1.Add(time.Hour)
+
+	testProjects = []*brigade.Project{
+		&brigade.Project{ID: "id2", Name: "Name2", Repository: "repo2", Namespace: "ns2", Worker: "worker2"},
+		&brigade.Project{ID: "id1", Name: "Name1", Repository: "repo1", Namespace: "ns1", Worker: "worker1"},
+	}
+	testBuilds = []*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "id1", Type: "push", Provider: "github", Version: "1234567890", Status: "Succeeded", Duration: time.Second, Start: t1},
+		&brigade.Build{ID: "bld2", ProjectID: "id1", Type: "push", Provider: "github", Version: "1234567891", Status: "Running", Start: t2},
+		&brigade.Build{ID: "bld3", ProjectID: "id2", Type: "deploy", Provider: "github", Version: "1234567892", Status: "Pending", Creation: t2.Add(time.Minute)},
+	}
+	testJobs = []*brigade.Job{
+		&brigade.Job{ID: "job2", BuildID: "bld1", Name: "test", Image: "golang", Status: "Succeeded", Creation: t2},
+		&brigade.Job{ID: "job1", BuildID: "bld1", Name: "build", Image: "golang", Status: "Succeeded", Creation: t1},
+		&brigade.Job{ID: "job3", BuildID: "bld2", Name: "build", Image: "golang", Status: "Running", Creation: t2},
+	}
+)
+
+func TestAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		err     error
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "Listing projects should return the projects sorted by name.",
+			url:     "/api/v1/projects",
+			expCode: http.StatusOK,
+			expBody: `{"items":[` +
+				`{"id":"id1","name":"Name1","repository":"repo1","namespace":"ns1","worker":"worker1","config":{"allow_privileged_jobs":false,"allow_host_mounts":false,"init_git_submodules":false,"default_script":false,"secrets":0,"build_storage_size":0,"build_storage_class":"","cache_storage_class":""}},` +
+				`{"id":"id2","name":"Name2","repository":"repo2","namespace":"ns2","worker":"worker2","config":{"allow_privileged_jobs":false,"allow_host_mounts":false,"init_git_submodules":false,"default_script":false,"secrets":0,"build_storage_size":0,"build_storage_class":"","cache_storage_class":""}}` +
+				`],"total":2,"limit":100,"offset":0}`,
+		},
+		{
+			name:    "Listing builds should return the most recently created builds first, even if they have not started.",
+			url:     "/api/v1/builds?limit=2",
+			expCode: http.StatusOK,
+			expBody: `{"items":[` +
+				`{"id":"bld3","project_id":"id2","type":"deploy","provider":"github","version":"1234567892","status":"Pending","duration_seconds":0,"creation":"2019-01-10T13:01:00Z","start":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z"},` +
+				`{"id":"bld2","project_id":"id1","type":"push","provider":"github","version":"1234567891","status":"Running","duration_seconds":0,"creation":"0001-01-01T00:00:00Z","start":"2019-01-10T13:00:00Z","end":"0001-01-01T00:00:00Z"}` +
+				`],"total":3,"limit":2,"offset":0}`,
+		},
+		{
+			name:    "Listing builds should paginate with the offset.",
+			url:     "/api/v1/builds?limit=2&offset=2",
+			expCode: http.StatusOK,
+			expBody: `{"items":[` +
+				`{"id":"bld1","project_id":"id1","type":"push","provider":"github","version":"1234567890","status":"Succeeded","duration_seconds":1,"creation":"0001-01-01T00:00:00Z","start":"2019-01-10T12:00:00Z","end":"0001-01-01T00:00:00Z"}` +
+				`],"total":3,"limit":2,"offset":2}`,
+		},
+		{
+			name:    "Listing builds after the last page should return an empty page.",
+			url:     "/api/v1/builds?offset=10",
+			expCode: http.StatusOK,
+			expBody: `{"items":[],"total":3,"limit":100,"offset":10}`,
+		},
+		{
+			name:    "Listing builds should filter by project and status.",
+			url:     "/api/v1/builds?project=id1&status=running",
+			expCode: http.StatusOK,
+			expBody: `{"items":[` +
+				`{"id":"bld2","project_id":"id1","type":"push","provider":"github","version":"1234567891","status":"Running","duration_seconds":0,"creation":"0001-01-01T00:00:00Z","start":"2019-01-10T13:00:00Z","end":"0001-01-01T00:00:00Z"}` +
+				`],"total":1,"limit":100,"offset":0}`,
+		},
+		{
+			name:    "Listing the jobs of a build should return its jobs sorted by creation.",
+			url:     "/api/v1/builds/bld1/jobs",
+			expCode: http.StatusOK,
+			expBody: `{"items":[` +
+				`{"id":"job1","build_id":"bld1","name":"build","image":"golang","status":"Succeeded","duration_seconds":0,"creation":"2019-01-10T12:00:00Z","start":"0001-01-01T00:00:00Z"},` +
+				`{"id":"job2","build_id":"bld1","name":"test","image":"golang","status":"Succeeded","duration_seconds":0,"creation":"2019-01-10T13:00:00Z","start":"0001-01-01T00:00:00Z"}` +
+				`],"total":2,"limit":100,"offset":0}`,
+		},
+		{
+			name:    "Listing the jobs of a missing build should fail.",
+			url:     "/api/v1/builds/bld9/jobs",
+			expCode: http.StatusNotFound,
+			expBody: `{"error":"build bld9 not found"}`,
+		},
+		{
+			name:    "Unknown paths should fail.",
+			url:     "/api/v1/jobs",
+			expCode: http.StatusNotFound,
+			expBody: `{"error":"/api/v1/jobs not found"}`,
+		},
+		{
+			name:    "Invalid pagination should fail.",
+			url:     "/api/v1/builds?limit=0",
+			expCode: http.StatusBadRequest,
+			expBody: `{"error":"invalid limit \"0\", it should be between 1 and 1000"}`,
+		},
+		{
+			name:    "Non read requests should fail.",
+			method:  "POST",
+			url:     "/api/v1/builds",
+			expCode: http.StatusMethodNotAllowed,
+			expBody: `{"error":"method POST not allowed"}`,
+		},
+		{
+			name:    "Errors getting the data from Brigade should fail.",
+			url:     "/api/v1/projects",
+			err:     fmt.Errorf("wanted error"),
+			expCode: http.StatusInternalServerError,
+			expBody: `{"error":"wanted error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			if test.err != nil {
+				mbsvc.On("GetProjects").Return(nil, test.err)
+			} else {
+				mbsvc.On("GetProjects").Return(testProjects, nil)
+			}
+			mbsvc.On("GetBuilds").Return(testBuilds, nil)
+			mbsvc.On("GetJobs").Return(testJobs, nil)
+
+			method := test.method
+			if method == "" {
+				method = "GET"
+			}
+
+			h := api.NewHandler(mbsvc, log.Dummy)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(method, test.url, nil)
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(test.expCode, rec.Code)
+			assert.Equal("application/json", rec.Header().Get("Content-Type"))
+			assert.JSONEq(test.expBody, rec.Body.String())
+		})
+	}
+}
+
+func TestAPISnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, Brigade is only requested by the collection.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Once().Return(testBuilds, nil)
+	mbsvc.On("GetJobs").Once().Return(testJobs, nil)
+
+	snapshot := brigade.NewSnapshot(mbsvc, time.Hour)
+	snapshot.GetBuilds()
+	snapshot.GetJobs()
+
+	h := api.NewHandler(snapshot.Cached(), log.Dummy)
+	for _, url := range []string{"/api/v1/builds", "/api/v1/builds/bld1/jobs", "/api/v1/builds/bld2/jobs"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+		assert.Equal(http.StatusOK, rec.Code)
+	}
+
+	mbsvc.AssertNumberOfCalls(t, "GetBuilds", 1)
+	mbsvc.AssertNumberOfCalls(t, "GetJobs", 1)
+}
@@ -13,54 +13,72 @@ const (
 	buildSubSystem = "build"
 )
 
+// BuildConfig is the configuration of the build subcollector.
+type BuildConfig struct {
+	// StatusStateSet will send the status metric of every known status for each build
+	// with 0 or 1 values, instead of a single metric with the current status.
+	StatusStateSet bool
+	// MaxSeries is the max number of build series, when exceeded the non terminal and
+	// most recent builds will be kept and the rest dropped. 0 means no limit.
+	MaxSeries int
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
 // build is the Brigade build subcollector. this colletor will collect
 // the metrics regarding brigade builds.
 // Satisfies internfal collector interface.
 type build struct {
+	cfg        BuildConfig
 	brigadeSVC brigade.Interface
 	logger     log.Logger
+	*seriesLimit
 
 	// Metrics.
-	buildInfoDesc     *prometheus.Desc
-	buildStatusDesc   *prometheus.Desc
-	buildDurationDesc *prometheus.Desc
+	buildInfoDesc     *metricDesc
+	buildStatusDesc   *metricDesc
+	buildDurationDesc *metricDesc
 }
 
 // NewBuild returns a new build subcollector.
-func NewBuild(brigadeSVC brigade.Interface, logger log.Logger) subcollector {
+func NewBuild(cfg BuildConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
 	return &build{
-		brigadeSVC: brigadeSVC,
-		logger:     logger,
+		cfg:         cfg,
+		brigadeSVC:  brigadeSVC,
+		logger:      logger,
+		seriesLimit: &seriesLimit{max: cfg.MaxSeries},
 
-		buildInfoDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, buildSubSystem, "info"),
+		buildInfoDesc: cfg.Metrics.newDesc(
+			buildSubSystem, "info",
 			"Brigade build information.",
-			[]string{"id", "project_id", "event_type", "provider", "version"}, nil,
+			[]string{"id", "project_id", "event_type", "provider", "version"},
 		),
-		buildStatusDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, buildSubSystem, "status"),
+		buildStatusDesc: cfg.Metrics.newDesc(
+			buildSubSystem, "status",
 			"Brigade build status.",
-			[]string{"id", "status"}, nil,
+			[]string{"id", "status"},
 		),
-		buildDurationDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, buildSubSystem, "duration_seconds"),
+		buildDurationDesc: cfg.Metrics.newDesc(
+			buildSubSystem, "duration_seconds",
 			"Brigade build duration in seconds.",
-			[]string{"id"}, nil,
+			[]string{"id"},
 		),
 	}
 }
 
-// Collect satisfies subcollector.
+// Collect satisfies Subcollector.
 func (b *build) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 	blds, err := b.brigadeSVC.GetBuilds()
 	if err != nil {
 		return err
 	}
 
+	// Info, duration and status series.
+	blds = b.limitBuilds(blds, 2+statusSeries(b.cfg.StatusStateSet))
+
 	for _, bld := range blds {
 		// Info metric.
-		err := sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			b.buildInfoDesc,
+		err := sendMetric(ctx, ch, b.buildInfoDesc.newConstMetric(
 			prometheus.GaugeValue,
 			1,
 			bld.ID, bld.ProjectID, bld.Type, bld.Provider, bld.Version))
@@ -70,11 +88,7 @@ func (b *build) Collect(ctx context.Context, ch chan<- prometheus.Metric) error
 		}
 
 		// Status metric.
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			b.buildStatusDesc,
-			prometheus.GaugeValue,
-			1,
-			bld.ID, bld.Status))
+		err = sendStatusMetric(ctx, ch, b.buildStatusDesc, b.cfg.StatusStateSet, bld.ID, bld.Status)
 
 		if err != nil {
 			return err
@@ -82,8 +96,7 @@ func (b *build) Collect(ctx context.Context, ch chan<- prometheus.Metric) error
 
 		// Duration metric.
 		// TODO: Think if it's 0 we should send the metric or not.
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			b.buildDurationDesc,
+		err = sendMetric(ctx, ch, b.buildDurationDesc.newConstMetric(
 			prometheus.GaugeValue,
 			bld.Duration.Seconds(),
 			bld.ID))
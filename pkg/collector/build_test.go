@@ -103,7 +103,7 @@ func TestBuildSubcollector(t *testing.T) {
 			mbsvc := &mbrigade.Interface{}
 			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
 
-			clr := collector.NewBuild(mbsvc, log.Dummy)
+			clr := collector.NewBuild(collector.BuildConfig{}, mbsvc, log.Dummy)
 
 			ch := make(chan prometheus.Metric)
 
@@ -123,3 +123,151 @@ func TestBuildSubcollector(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSubcollectorStatusStateSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        collector.BuildConfig
+		builds     []*brigade.Build
+		expMetrics []metricResult
+	}{
+		{
+			name: "With state set disabled the status metric should be sent as it is.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", Status: "Running"},
+				&brigade.Build{ID: "id2", Status: "Exploded"},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Exploded"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+		{
+			name: "With state set enabled every known status should be sent and the unknown statuses normalized.",
+			cfg:  collector.BuildConfig{StatusStateSet: true},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", Status: "Running"},
+				&brigade.Build{ID: "id2", Status: "Exploded"},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Pending"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Succeeded"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Failed"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Unknown"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Pending"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Running"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Succeeded"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Failed"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Unknown"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+
+			clr := collector.NewBuild(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the status metrics.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == buildStatusDesc {
+					got = append(got, mr)
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestBuildSubcollectorSeriesLimit(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		cfg        collector.BuildConfig
+		builds     []*brigade.Build
+		expMetrics []metricResult
+	}{
+		{
+			name: "Without limit all the builds should be collected.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", Status: "Succeeded", Start: now.Add(-3 * time.Hour)},
+				&brigade.Build{ID: "id2", Status: "Failed", Start: now.Add(-1 * time.Hour)},
+				&brigade.Build{ID: "id3", Status: "Running", Start: now.Add(-2 * time.Hour)},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id1", "status": "Succeeded"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Failed"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id3", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+		{
+			name: "With a limit the non terminal and most recent builds should be collected.",
+			cfg:  collector.BuildConfig{MaxSeries: 6},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", Status: "Succeeded", Start: now.Add(-3 * time.Hour)},
+				&brigade.Build{ID: "id2", Status: "Failed", Start: now.Add(-1 * time.Hour)},
+				&brigade.Build{ID: "id3", Status: "Running", Start: now.Add(-2 * time.Hour)},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id3", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: buildStatusDesc, labels: labelMap{"id": "id2", "status": "Failed"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+		{
+			name: "With a limit lower than the series of a build no builds should be collected.",
+			cfg:  collector.BuildConfig{MaxSeries: 2},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", Status: "Running", Start: now.Add(-3 * time.Hour)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+
+			clr := collector.NewBuild(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the status metrics.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == buildStatusDesc {
+					got = append(got, mr)
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// subcollectorCache has the metrics of the last successful collection of
+// a subcollector.
+type subcollectorCache struct {
+	sync.Mutex
+
+	interval time.Duration
+	metrics  []prometheus.Metric
+	updated  time.Time
+}
+
+// fresh returns if the cached metrics have been refreshed before the interval passed.
+func (s *subcollectorCache) fresh() bool {
+	return !s.updated.IsZero() && time.Since(s.updated) < s.interval
+}
@@ -3,6 +3,9 @@ package collector
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,18 +21,66 @@ const (
 
 	// Defaults.
 	collectTimeoutDef = 10 * time.Second
+	versionDef        = "unknown"
 )
 
 // Config is the Exporter configuration.
 type Config struct {
-	// CollectTimeout is the timeout to collect the metrics.
+	// Version is the version of the exporter.
+	Version string
+	// Metrics is the configuration of the metric names and labels of all the Brigade
+	// subcollectors and the exporter.
+	Metrics MetricsConfig
+	// CollectTimeout is the default timeout of the subcollectors to collect the metrics.
 	CollectTimeout time.Duration
+	// SubcollectorTimeouts are the timeouts of the subcollectors by name, these
+	// override the default CollectTimeout.
+	SubcollectorTimeouts map[string]time.Duration
+	// SubcollectorIntervals are the minimum refresh intervals of the subcollectors by
+	// name, until the interval passes the subcollector will serve cached metrics.
+	SubcollectorIntervals map[string]time.Duration
+	// SubcollectorMaxSeries are the max number of series of the subcollectors by name,
+	// 0 means no limit. The series over the limit are dropped, the builds and jobs
+	// subcollectors keep the non terminal and the most recent ones first.
+	SubcollectorMaxSeries map[string]int
+	// Subcollectors are custom subcollectors by name that will be collected
+	// by the exporter along with the Brigade subcollectors.
+	Subcollectors map[string]Subcollector
+	// DisabledSubcollectors are the names of the subcollectors that will be disabled.
+	DisabledSubcollectors []string
 	// DisableProjects will disable the project metrics subcollector.
 	DisableProjects bool
+	// BlessedWorkerImages are the worker images that the projects should use.
+	BlessedWorkerImages []string
+	// DefaultWorkerImage is the worker image of the projects without a custom worker.
+	DefaultWorkerImage string
 	// DisableBuilds will disable the builds metrics subcollector.
 	DisableBuilds bool
+	// BuildsMaxSeries is the max number of series of the builds subcollector, 0 means no
+	// limit. It overrides the builds SubcollectorMaxSeries.
+	BuildsMaxSeries int
 	// DisableJobs will disable the Jobs metrics subcollector.
 	DisableJobs bool
+	// JobsMaxSeries is the max number of series of the jobs subcollector, 0 means no
+	// limit. It overrides the jobs SubcollectorMaxSeries.
+	JobsMaxSeries int
+	// StatusStateSet will send the builds and jobs status metrics for every known
+	// status with 0 or 1 values instead of a single metric with the current status.
+	StatusStateSet bool
+	// EnableFlakyJobs will enable the flaky jobs metrics subcollector.
+	EnableFlakyJobs bool
+	// FlakyJobWindow is the sliding window used to calculate the jobs flakiness.
+	FlakyJobWindow time.Duration
+	// EnableBuildSuccessRatio will enable the build success ratio metrics subcollector.
+	EnableBuildSuccessRatio bool
+	// BuildSuccessRatioWindows are the sliding windows used to calculate the build success ratio.
+	BuildSuccessRatioWindows []time.Duration
+	// EnableDORA will enable the DORA metrics subcollector.
+	EnableDORA bool
+	// DORADeployEventTypes are the build event types that count as deployments.
+	DORADeployEventTypes []string
+	// DORAWindow is the sliding window used to calculate the DORA metrics.
+	DORAWindow time.Duration
 }
 
 // defaults sets the required defaults.
@@ -37,126 +88,562 @@ func (c *Config) defaults() {
 	if c.CollectTimeout == 0 {
 		c.CollectTimeout = collectTimeoutDef
 	}
+
+	if c.Version == "" {
+		c.Version = versionDef
+	}
 }
 
 // Exporter is the main exporter that implements the prometheus.Collector interface
 // and executes the other collectors
 type Exporter struct {
-	scrapeDurationDesc *prometheus.Desc
-	scrapeSuccessDesc  *prometheus.Desc
+	upDesc             *metricDesc
+	buildInfoDesc      *metricDesc
+	scrapeDurationDesc *metricDesc
+	scrapeSuccessDesc  *metricDesc
+	seriesDroppedDesc  *metricDesc
+	seriesLimitHitDesc *metricDesc
+	cacheAgeDesc       *metricDesc
 
 	// Subcollectors.
-	subcolls map[string]subcollector
+	subcolls        map[string]Subcollector
+	subcollCfgs     map[string]interface{}
+	brigadeSubcolls map[string]bool
+	caches          map[string]*subcollectorCache
+	limits          map[string]seriesLimiter
+
+	// State, shared with the filtered exporters.
+	state *exporterState
 
 	cfg    Config
 	logger log.Logger
 }
 
+// exporterState is the state of the exporter based on the collections.
+type exporterState struct {
+	mu       sync.Mutex
+	up       bool
+	upKnown  bool
+	ready    bool
+	statuses map[string]SubcollectorStatus
+}
+
+// SubcollectorStatus is the status of the last collection of a subcollector.
+type SubcollectorStatus struct {
+	// Name is the name of the subcollector.
+	Name string
+	// LastCollection is when the subcollector was collected for the last time, zero
+	// if it has never been collected. The cached metrics don't update it.
+	LastCollection time.Time
+	// Duration is the duration of the last collection.
+	Duration time.Duration
+	// Success is true if the last collection succeeded.
+	Success bool
+	// Error is the error message of the last collection if it failed.
+	Error string
+}
+
+// stateful is implemented by the subcollectors that keep state between the
+// collections, so a reloaded exporter doesn't lose it.
+type stateful interface {
+	// inherit takes the state of the previous subcollector.
+	inherit(prev Subcollector)
+}
+
 // NewExporter returns a new exporter.
-func NewExporter(cfg Config, brigadeSVC brigade.Interface, logger log.Logger) prometheus.Collector {
+func NewExporter(cfg Config, brigadeSVC brigade.Interface, logger log.Logger) *Exporter {
+	return newExporter(cfg, brigadeSVC, logger, nil)
+}
+
+// Reload returns the exporter with the new configuration. If the configuration
+// didn't change the same exporter is returned, otherwise a new exporter that
+// shares the state of this one (up, ready and the subcollector statuses) is
+// returned. Only the subcollectors whose configuration changed are recreated,
+// the recreated ones inherit the state of the previous ones.
+func (e *Exporter) Reload(cfg Config, brigadeSVC brigade.Interface, logger log.Logger) *Exporter {
+	cfg.defaults()
+	if reflect.DeepEqual(cfg, e.cfg) {
+		return e
+	}
+
+	return newExporter(cfg, brigadeSVC, logger, e)
+}
+
+func newExporter(cfg Config, brigadeSVC brigade.Interface, logger log.Logger, prev *Exporter) *Exporter {
 	// Fill the required defaults.
 	cfg.defaults()
 
 	exporter := &Exporter{
-		scrapeDurationDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "exporter", "collector_duration_seconds"),
+		upDesc: cfg.Metrics.newDesc(
+			"", "up",
+			"Whether Brigade is reachable.",
+			nil,
+		),
+
+		buildInfoDesc: cfg.Metrics.newDesc(
+			"exporter", "build_info",
+			"Exporter build information.",
+			[]string{"version", "goversion"},
+		),
+
+		scrapeDurationDesc: cfg.Metrics.newDesc(
+			"exporter", "collector_duration_seconds",
 			"Collector time duration.",
 			[]string{"collector"},
-			nil,
 		),
 
-		scrapeSuccessDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "exporter", "collector_success"),
+		scrapeSuccessDesc: cfg.Metrics.newDesc(
+			"exporter", "collector_success",
 			"Whether a collector succeeded.",
 			[]string{"collector"},
-			nil,
 		),
+
+		seriesDroppedDesc: cfg.Metrics.newDesc(
+			"exporter", "series_dropped_total",
+			"Number of series dropped by a collector due to the series limit.",
+			[]string{"collector"},
+		),
+
+		seriesLimitHitDesc: cfg.Metrics.newDesc(
+			"exporter", "series_limit_hit",
+			"Whether a collector hit the series limit on the last collection.",
+			[]string{"collector"},
+		),
+
+		cacheAgeDesc: cfg.Metrics.newDesc(
+			"exporter", "collector_cache_age_seconds",
+			"Time since the cached metrics of a collector were refreshed in seconds.",
+			[]string{"collector"},
+		),
+		state:  &exporterState{statuses: map[string]SubcollectorStatus{}},
 		cfg:    cfg,
 		logger: logger,
 	}
 
-	exporter.initSubcollectors(brigadeSVC)
+	if prev != nil {
+		exporter.state = prev.state
+	}
+
+	exporter.initSubcollectors(brigadeSVC, prev)
 	return exporter
 }
 
-func (e *Exporter) initSubcollectors(brigadeSVC brigade.Interface) {
-	e.subcolls = map[string]subcollector{}
+// subcollector returns the subcollector of the previous exporter if its
+// configuration didn't change, otherwise a new one that inherits the state
+// of the previous one.
+func (e *Exporter) subcollector(prev *Exporter, name string, cfg interface{}, newSubcoll func() Subcollector) Subcollector {
+	e.subcollCfgs[name] = cfg
+	if prev == nil {
+		return newSubcoll()
+	}
+
+	old, ok := prev.subcolls[name]
+	if !ok {
+		return newSubcoll()
+	}
+	if reflect.DeepEqual(prev.subcollCfgs[name], cfg) {
+		return old
+	}
+
+	sc := newSubcoll()
+	if st, ok := sc.(stateful); ok {
+		st.inherit(old)
+	}
+	return sc
+}
+
+func (e *Exporter) initSubcollectors(brigadeSVC brigade.Interface, prev *Exporter) {
+	e.subcolls = map[string]Subcollector{}
+	e.subcollCfgs = map[string]interface{}{}
 
 	// Generate subcollectors.
 	if !e.cfg.DisableProjects {
-		e.subcolls["projects"] = NewProject(brigadeSVC, e.logger.With("collector", "projects"))
+		cfg := ProjectConfig{
+			BlessedWorkerImages: e.cfg.BlessedWorkerImages,
+			DefaultWorkerImage:  e.cfg.DefaultWorkerImage,
+			Metrics:             e.cfg.Metrics,
+		}
+		e.subcolls["projects"] = e.subcollector(prev, "projects", cfg, func() Subcollector {
+			return NewProject(cfg, brigadeSVC, e.logger.With("collector", "projects"))
+		})
 	} else {
 		e.logger.Warnf("projects collector disabled")
 	}
 
 	if !e.cfg.DisableBuilds {
-		e.subcolls["builds"] = NewBuild(brigadeSVC, e.logger.With("collector", "builds"))
+		cfg := BuildConfig{StatusStateSet: e.cfg.StatusStateSet, MaxSeries: e.maxSeries("builds", e.cfg.BuildsMaxSeries), Metrics: e.cfg.Metrics}
+		e.subcolls["builds"] = e.subcollector(prev, "builds", cfg, func() Subcollector {
+			return NewBuild(cfg, brigadeSVC, e.logger.With("collector", "builds"))
+		})
 	} else {
 		e.logger.Warnf("builds collector disabled")
 	}
 	if !e.cfg.DisableJobs {
-		e.subcolls["jobs"] = NewJob(brigadeSVC, e.logger.With("collector", "jobs"))
+		cfg := JobConfig{StatusStateSet: e.cfg.StatusStateSet, MaxSeries: e.maxSeries("jobs", e.cfg.JobsMaxSeries), Metrics: e.cfg.Metrics}
+		e.subcolls["jobs"] = e.subcollector(prev, "jobs", cfg, func() Subcollector {
+			return NewJob(cfg, brigadeSVC, e.logger.With("collector", "jobs"))
+		})
 	} else {
 		e.logger.Warnf("jobs collector disabled")
 	}
+
+	if e.cfg.EnableFlakyJobs {
+		cfg := FlakyJobConfig{Window: e.cfg.FlakyJobWindow, Metrics: e.cfg.Metrics}
+		e.subcolls["flaky_jobs"] = e.subcollector(prev, "flaky_jobs", cfg, func() Subcollector {
+			return NewFlakyJob(cfg, brigadeSVC, e.logger.With("collector", "flaky_jobs"))
+		})
+	}
+
+	if e.cfg.EnableBuildSuccessRatio {
+		cfg := BuildSuccessRatioConfig{Windows: e.cfg.BuildSuccessRatioWindows, Metrics: e.cfg.Metrics}
+		e.subcolls["build_success_ratio"] = e.subcollector(prev, "build_success_ratio", cfg, func() Subcollector {
+			return NewBuildSuccessRatio(cfg, brigadeSVC, e.logger.With("collector", "build_success_ratio"))
+		})
+	}
+
+	if e.cfg.EnableDORA {
+		cfg := DORAConfig{DeployEventTypes: e.cfg.DORADeployEventTypes, Window: e.cfg.DORAWindow, Metrics: e.cfg.Metrics}
+		e.subcolls["dora"] = e.subcollector(prev, "dora", cfg, func() Subcollector {
+			return NewDORA(cfg, brigadeSVC, e.logger.With("collector", "dora"))
+		})
+	}
+
+	// Track the Brigade subcollectors to know if Brigade is reachable.
+	e.brigadeSubcolls = map[string]bool{}
+	for name := range e.subcolls {
+		e.brigadeSubcolls[name] = true
+	}
+
+	// Register the custom subcollectors.
+	for name, sc := range e.cfg.Subcollectors {
+		if _, ok := e.subcolls[name]; ok {
+			e.logger.Errorf("ignoring %s custom collector, already registered", name)
+			continue
+		}
+		e.subcolls[name] = sc
+	}
+
+	// Disable subcollectors by name.
+	for _, name := range e.cfg.DisabledSubcollectors {
+		if _, ok := e.subcolls[name]; !ok {
+			e.logger.Warnf("can't disable unknown %s collector", name)
+			continue
+		}
+		delete(e.subcolls, name)
+		delete(e.brigadeSubcolls, name)
+		e.logger.Warnf("%s collector disabled", name)
+	}
+
+	// Only the subcollectors with a refresh interval are cached.
+	e.caches = map[string]*subcollectorCache{}
+	for name, interval := range e.cfg.SubcollectorIntervals {
+		if _, ok := e.subcolls[name]; !ok {
+			e.logger.Warnf("refresh interval set for unknown %s collector", name)
+			continue
+		}
+		if interval <= 0 {
+			continue
+		}
+		// The cached metrics are kept if the subcollector was kept.
+		if c, ok := prev.cache(name); ok && c.interval == interval && e.kept(prev, name) {
+			e.caches[name] = c
+			continue
+		}
+		e.caches[name] = &subcollectorCache{interval: interval}
+	}
+
+	// The subcollectors that limit their series know what series to keep, the
+	// rest are limited when gathered.
+	e.limits = map[string]seriesLimiter{}
+	for name, sc := range e.subcolls {
+		if sl, ok := sc.(seriesLimiter); ok && sl.maxSeries() > 0 {
+			e.limits[name] = sl
+		} else if max := e.cfg.SubcollectorMaxSeries[name]; max > 0 {
+			// Keep the dropped series counter if the limit didn't change.
+			if sl, ok := prev.limit(name).(*seriesLimit); ok && sl.max == max {
+				e.limits[name] = sl
+				continue
+			}
+			e.limits[name] = &seriesLimit{max: max}
+		}
+	}
+	for name := range e.cfg.SubcollectorMaxSeries {
+		if _, ok := e.subcolls[name]; !ok {
+			e.logger.Warnf("max series set for unknown %s collector", name)
+		}
+	}
+}
+
+// kept returns true if the subcollector is the same one the previous exporter had.
+func (e *Exporter) kept(prev *Exporter, name string) bool {
+	if prev == nil {
+		return false
+	}
+	_, ok := e.subcollCfgs[name]
+	return ok && reflect.DeepEqual(prev.subcollCfgs[name], e.subcollCfgs[name])
+}
+
+// cache returns the cache of a subcollector, it can be called on a nil exporter.
+func (e *Exporter) cache(name string) (*subcollectorCache, bool) {
+	if e == nil {
+		return nil, false
+	}
+	c, ok := e.caches[name]
+	return c, ok
+}
+
+// limit returns the series limiter of a subcollector, it can be called on a nil exporter.
+func (e *Exporter) limit(name string) seriesLimiter {
+	if e == nil {
+		return nil
+	}
+	return e.limits[name]
+}
+
+// maxSeries returns the max series of a subcollector, max if set or the subcollector max series.
+func (e *Exporter) maxSeries(name string, max int) int {
+	if max > 0 {
+		return max
+	}
+	return e.cfg.SubcollectorMaxSeries[name]
 }
 
 // Describe satisfies prometheus.Collector interface.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	ch <- e.scrapeDurationDesc
-	ch <- e.scrapeSuccessDesc
-
+	ch <- e.upDesc.desc
+	ch <- e.buildInfoDesc.desc
+	ch <- e.scrapeDurationDesc.desc
+	ch <- e.scrapeSuccessDesc.desc
+	ch <- e.seriesDroppedDesc.desc
+	ch <- e.seriesLimitHitDesc.desc
+	ch <- e.cacheAgeDesc.desc
 }
 
 // Collect satisfies prometheus.Collector interface.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	e.logger.Debugf("starting collect")
-	var wg sync.WaitGroup
+	ch <- e.buildInfoDesc.newConstMetric(prometheus.GaugeValue, 1, e.cfg.Version, runtime.Version())
 
-	// Create a context with a timeout so long metrics gathers can
-	// be stopped.
-	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.CollectTimeout)
-	defer cancel()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var brigadeTried, brigadeSucceeded bool
 
 	// Call all the subcollectors.
 	wg.Add(len(e.subcolls))
 	for scName, sc := range e.subcolls {
-		go func(scName string, sc subcollector) {
+		go func(scName string, sc Subcollector) {
 			defer wg.Done()
-			e.subcollect(ctx, scName, sc, ch)
+			collected, success := e.subcollect(scName, sc, ch)
+
+			// Brigade is reachable if any of the Brigade subcollectors succeeded.
+			if collected && e.brigadeSubcolls[scName] {
+				mu.Lock()
+				brigadeTried = true
+				brigadeSucceeded = brigadeSucceeded || success
+				mu.Unlock()
+			}
 		}(scName, sc)
 	}
 
 	// Wait for all subscrapes.
 	wg.Wait()
+
+	// If all the Brigade subcollectors have been served from cache (or none
+	// have been collected) we maintain the last known state.
+	st := e.state
+	st.mu.Lock()
+	if brigadeTried {
+		st.up = brigadeSucceeded
+		st.upKnown = true
+	}
+	if st.up || len(e.brigadeSubcolls) == 0 {
+		st.ready = true
+	}
+	up, upKnown := st.up, st.upKnown
+	st.mu.Unlock()
+
+	if upKnown {
+		ch <- e.upDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(up))
+	}
+
 	e.logger.Debugf("finished collect")
 }
 
-func (e *Exporter) subcollect(ctx context.Context, scName string, sc subcollector, ch chan<- prometheus.Metric) {
+// Ready returns if the exporter has made the first collection
+// with Brigade reachable.
+func (e *Exporter) Ready() bool {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return e.state.ready
+}
+
+// SubcollectorStatuses returns the status of the last collection of the enabled
+// subcollectors sorted by name.
+func (e *Exporter) SubcollectorStatuses() []SubcollectorStatus {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+
+	names := e.Subcollectors()
+	statuses := make([]SubcollectorStatus, len(names))
+	for i, name := range names {
+		st, ok := e.state.statuses[name]
+		if !ok {
+			st = SubcollectorStatus{Name: name}
+		}
+		statuses[i] = st
+	}
+	return statuses
+}
+
+// Subcollectors returns the names of the enabled subcollectors.
+func (e *Exporter) Subcollectors() []string {
+	names := make([]string, 0, len(e.subcolls))
+	for name := range e.subcolls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filtered returns an exporter that only collects the subcollectors with the
+// names. The filtered exporter shares the subcollectors, caches and state with
+// the exporter so it's cheap to create one per scrape.
+func (e *Exporter) Filtered(names []string) (*Exporter, error) {
+	subcolls := map[string]Subcollector{}
+	for _, name := range names {
+		sc, ok := e.subcolls[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or disabled %s collector", name)
+		}
+		subcolls[name] = sc
+	}
+
+	f := *e
+	f.subcolls = subcolls
+	return &f, nil
+}
+
+// subcollect will collect the metrics of the subcollector and return if the subcollector
+// has been collected (not served from cache) and if the collection succeeded.
+func (e *Exporter) subcollect(scName string, sc Subcollector, ch chan<- prometheus.Metric) (collected bool, success bool) {
 	logger := e.logger.With("collector", scName)
+
+	// If the subcollector is cached and has been refreshed recently
+	// we don't need to collect again.
+	cache, cached := e.caches[scName]
+	if cached {
+		cache.Lock()
+		defer cache.Unlock()
+
+		if cache.fresh() {
+			logger.Debugf("serving cached subcollection")
+			for _, m := range cache.metrics {
+				ch <- m
+			}
+			ch <- e.cacheAgeDesc.newConstMetric(prometheus.GaugeValue, time.Since(cache.updated).Seconds(), scName)
+			return false, true
+		}
+	}
+
 	logger.Debugf("starting subcollection")
 
+	// Create a context with a timeout so long metrics gathers can
+	// be stopped.
+	timeout := e.cfg.CollectTimeout
+	if t, ok := e.cfg.SubcollectorTimeouts[scName]; ok && t > 0 {
+		timeout = t
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Gather the metrics so we can cache them.
 	startTime := time.Now()
-	err := sc.Collect(ctx, ch)
+	metrics, err := gather(ctx, sc)
+	limit, limited := e.limits[scName]
+	if sl, ok := limit.(*seriesLimit); ok {
+		metrics = sl.limitMetrics(metrics)
+	}
+
+	duration := time.Since(startTime)
 
-	var success float64 = 1
+	var successValue float64 = 1
+	status := SubcollectorStatus{Name: scName, LastCollection: startTime, Duration: duration, Success: true}
 	if err != nil {
 		logger.Errorf("subcollection failed: %s", err)
-		success = 0
+		successValue = 0
+		status.Success = false
+		status.Error = err.Error()
+	}
+	e.state.mu.Lock()
+	e.state.statuses[scName] = status
+	e.state.mu.Unlock()
+
+	metrics = append(metrics,
+		e.scrapeDurationDesc.newConstMetric(prometheus.GaugeValue, duration.Seconds(), scName),
+		e.scrapeSuccessDesc.newConstMetric(prometheus.GaugeValue, successValue, scName),
+	)
+
+	// Series limit metrics only for the limited subcollectors.
+	if limited {
+		dropped, limitHit := limit.seriesDropped()
+		metrics = append(metrics,
+			e.seriesDroppedDesc.newConstMetric(prometheus.CounterValue, dropped, scName),
+			e.seriesLimitHitDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(limitHit), scName),
+		)
+	}
+
+	for _, m := range metrics {
+		ch <- m
+	}
+
+	// Only cache the successful subcollections, the failed ones will be retried on the next collect.
+	if cached && err == nil {
+		cache.metrics = metrics
+		cache.updated = time.Now()
+		ch <- e.cacheAgeDesc.newConstMetric(prometheus.GaugeValue, 0, scName)
 	}
 
-	ch <- prometheus.MustNewConstMetric(e.scrapeDurationDesc, prometheus.GaugeValue, time.Since(startTime).Seconds(), scName)
-	ch <- prometheus.MustNewConstMetric(e.scrapeSuccessDesc, prometheus.GaugeValue, success, scName)
+	return true, err == nil
+}
+
+// gather will collect the metrics of the subcollector.
+func gather(ctx context.Context, sc Subcollector) ([]prometheus.Metric, error) {
+	metrics := []prometheus.Metric{}
+	scCh := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range scCh {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+
+	err := sc.Collect(ctx, scCh)
+	close(scCh)
+	<-done
+
+	// The metrics with dropped labels could be duplicated.
+	return aggregate(metrics), err
 }
 
-// subcollector is an internal type of collector that allows us to
-// collect custmizing the collection pieces and track if the collect
-// process failed and.
-type subcollector interface {
+// Subcollector is a type of collector that allows us to collect customizing
+// the collection pieces and track if the collect process failed. The exporter
+// will instrument the subcollectors, custom subcollectors can be registered
+// using the exporter Config. The subcollectors should stop sending metrics
+// when the context is done.
+type Subcollector interface {
 	// Collect will collect and return if the collection has been made successfully.
 	Collect(ctx context.Context, ch chan<- prometheus.Metric) error
 }
 
+// SubcollectorFunc is a helper to create subcollectors from functions.
+type SubcollectorFunc func(ctx context.Context, ch chan<- prometheus.Metric) error
+
+// Collect satisfies Subcollector.
+func (s SubcollectorFunc) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	return s(ctx, ch)
+}
+
 // sendMetric will send a metric but will check first if the context is active or has been finished.
 // this method will avoid that gourouties continue sending metrics after the gathering process has
 // been finished and avoid leaking background gourotines.
@@ -171,3 +658,21 @@ func sendMetric(ctx context.Context, ch chan<- prometheus.Metric, metric prometh
 	ch <- metric
 	return nil
 }
+
+// getUnix returns the unix timestamp of the time, if the time is not set it will return 0.
+func getUnix(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return float64(t.Unix())
+}
+
+// boolToFloat returns 1 if true and 0 if false.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -1,10 +1,12 @@
 package collector_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"testing"
 	"time"
 
@@ -39,6 +41,7 @@ var (
 		&brigade.Job{ID: "id2", BuildID: "bld2", Name: "id-name-2", Image: "image2", Status: "Pending", Duration: 340 * time.Second, Creation: t2, Start: t3},
 		&brigade.Job{ID: "id3", BuildID: "bld3", Name: "id-name-3", Image: "image3", Status: "Failed", Duration: 18 * time.Second},
 	}
+	testCustomDesc = prometheus.NewDesc("brigade_custom_cost", "Custom cost.", []string{"project_id"}, nil)
 )
 
 func TestExporter(t *testing.T) {
@@ -59,6 +62,8 @@ func TestExporter(t *testing.T) {
 			jobs:     testJobs,
 			expMetrics: []string{
 				// Exporter metrics.
+				`brigade_up 1`,
+				fmt.Sprintf(`brigade_exporter_build_info{goversion="%s",version="unknown"} 1`, runtime.Version()),
 				`brigade_exporter_collector_success{collector="projects"} 1`,
 				`brigade_exporter_collector_success{collector="builds"} 1`,
 				`brigade_exporter_collector_success{collector="jobs"} 1`,
@@ -67,6 +72,8 @@ func TestExporter(t *testing.T) {
 				`brigade_project_info{id="id1",name="Name1",namespace="ns1",repository="repo1",worker="worker1"} 1`,
 				`brigade_project_info{id="id2",name="Name2",namespace="ns2",repository="repo2",worker="worker2"} 1`,
 				`brigade_project_info{id="id3",name="Name3",namespace="ns3",repository="repo3",worker="worker3"} 1`,
+				`brigade_project_last_build_status{id="id1",status="None"} 1`,
+				`brigade_project_time_since_last_successful_build_seconds{id="id1"} +Inf`,
 
 				// Brigade builds metrics.
 				`brigade_build_info{event_type="deploy",id="id3",project_id="prj3",provider="toilet",version="1234567892"} 1`,
@@ -201,6 +208,190 @@ func TestExporter(t *testing.T) {
 				`brigade_project_info{id="id3",name="Name3",namespace="ns3",repository="repo3",worker="worker3"} 1`,
 			},
 		},
+		{
+			name:     "Limiting the series of the subcollectors should keep the non terminal objects and drop the rest.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				BuildsMaxSeries: 7,
+				JobsMaxSeries:   100,
+			},
+			expMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_series_dropped_total{collector="builds"} 3`,
+				`brigade_exporter_series_limit_hit{collector="builds"} 1`,
+				`brigade_exporter_series_dropped_total{collector="jobs"} 0`,
+				`brigade_exporter_series_limit_hit{collector="jobs"} 0`,
+
+				// Brigade builds metrics.
+				`brigade_build_info{event_type="pull_request",id="id2",project_id="prj2",provider="github",version="1234567891"} 1`,
+				`brigade_build_info{event_type="push",id="id1",project_id="prj1",provider="gitlab",version="1234567890"} 1`,
+
+				// Brigade Jobs metrics.
+				`brigade_job_status{id="id3",status="Failed"} 1`,
+			},
+			notExpMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_series_dropped_total{collector="projects"}`,
+				`brigade_exporter_series_limit_hit{collector="projects"}`,
+
+				// Brigade builds metrics.
+				`brigade_build_info{event_type="deploy",id="id3",project_id="prj3",provider="toilet",version="1234567892"} 1`,
+				`brigade_build_status{id="id3",status="Failed"} 1`,
+			},
+		},
+		{
+			name:     "Limiting the series of any subcollector should drop the series over the limit.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				SubcollectorMaxSeries: map[string]int{"projects": 5, "builds": 7},
+			},
+			expMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_series_dropped_total{collector="projects"} 37`,
+				`brigade_exporter_series_limit_hit{collector="projects"} 1`,
+				`brigade_exporter_series_dropped_total{collector="builds"} 3`,
+				`brigade_exporter_series_limit_hit{collector="builds"} 1`,
+
+				// Brigade projects metrics.
+				`brigade_project_info{id="id1",name="Name1",namespace="ns1",repository="repo1",worker="worker1"} 1`,
+
+				// Brigade builds metrics.
+				`brigade_build_info{event_type="pull_request",id="id2",project_id="prj2",provider="github",version="1234567891"} 1`,
+			},
+			notExpMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_series_dropped_total{collector="jobs"}`,
+				`brigade_exporter_series_limit_hit{collector="jobs"}`,
+
+				// Brigade projects metrics.
+				`brigade_project_info{id="id2",name="Name2",namespace="ns2",repository="repo2",worker="worker2"} 1`,
+				`brigade_project_worker_images`,
+
+				// Brigade builds metrics.
+				`brigade_build_info{event_type="deploy",id="id3",project_id="prj3",provider="toilet",version="1234567892"} 1`,
+			},
+		},
+		{
+			name:     "A timeout in a single subcollector should only return bad collector success on that subcollector.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				SubcollectorTimeouts: map[string]time.Duration{
+					"jobs": 1, // 1 nanosecond is almost a timeout.
+				},
+			},
+			expMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_collector_success{collector="projects"} 1`,
+				`brigade_exporter_collector_success{collector="builds"} 1`,
+				`brigade_exporter_collector_success{collector="jobs"} 0`,
+			},
+			notExpMetrics: []string{
+				// Brigade Jobs metrics.
+				`brigade_job_info{build_id="bld1",id="id1",image="image1",name="id-name-1"} 1`,
+			},
+		},
+		{
+			name:     "Custom subcollectors should be collected and instrumented along with the Brigade ones and disabled by name.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				Subcollectors: map[string]collector.Subcollector{
+					"custom": collector.SubcollectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+						ch <- prometheus.MustNewConstMetric(testCustomDesc, prometheus.GaugeValue, 42, "prj1")
+						return nil
+					}),
+					"custom_failing": collector.SubcollectorFunc(func(ctx context.Context, ch chan<- prometheus.Metric) error {
+						return fmt.Errorf("wanted error")
+					}),
+				},
+				DisabledSubcollectors: []string{"jobs", "builds"},
+			},
+			expMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_collector_success{collector="projects"} 1`,
+				`brigade_exporter_collector_success{collector="custom"} 1`,
+				`brigade_exporter_collector_success{collector="custom_failing"} 0`,
+				`brigade_exporter_collector_duration_seconds{collector="custom"}`,
+
+				// Custom metrics.
+				`brigade_custom_cost{project_id="prj1"} 42`,
+			},
+			notExpMetrics: []string{
+				// Exporter metrics.
+				`brigade_exporter_collector_success{collector="builds"}`,
+				`brigade_exporter_collector_success{collector="jobs"}`,
+
+				// Brigade Jobs metrics.
+				`brigade_job_info{build_id="bld1",id="id1",image="image1",name="id-name-1"} 1`,
+			},
+		},
+		{
+			name:     "The metrics configuration should be applied to all the metrics.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				DisableProjects: true,
+				Metrics: collector.MetricsConfig{
+					Namespace:    "ci",
+					Prefix:       "team_",
+					ConstLabels:  map[string]string{"cluster": "prod-1"},
+					RenameLabels: map[string]string{"version": "commit"},
+					DropLabels:   []string{"image"},
+				},
+			},
+			expMetrics: []string{
+				// Exporter metrics.
+				`team_ci_exporter_collector_success{cluster="prod-1",collector="builds"} 1`,
+
+				// Brigade builds metrics.
+				`team_ci_build_info{cluster="prod-1",commit="1234567890",event_type="push",id="id1",project_id="prj1",provider="gitlab"} 1`,
+				`team_ci_build_status{cluster="prod-1",id="id1",status="Running"} 1`,
+
+				// Brigade Jobs metrics.
+				`team_ci_job_info{build_id="bld1",cluster="prod-1",id="id1",name="id-name-1"} 1`,
+			},
+			notExpMetrics: []string{
+				`brigade_`,
+				`version="1234567890"`,
+				`image=`,
+			},
+		},
+		{
+			name:     "The metrics with labels not allowed should drop them and be aggregated with the aggregation of the metric.",
+			projects: testProjects,
+			builds:   testBuilds,
+			jobs:     testJobs,
+			exporterCfg: collector.Config{
+				DisableProjects: true,
+				Metrics: collector.MetricsConfig{
+					AllowedLabels: map[string][]string{
+						"brigade_build_info":           []string{},
+						"brigade_job_info":             []string{"id", "build_id"},
+						"brigade_job_duration_seconds": []string{},
+					},
+					DropLabels: []string{"id"},
+				},
+			},
+			expMetrics: []string{
+				// Brigade builds metrics.
+				`brigade_build_info 3`,
+				`brigade_build_status{status="Failed"} 1`,
+				`brigade_build_duration_seconds 340`,
+
+				// Brigade Jobs metrics.
+				`brigade_job_info{build_id="bld1"} 1`,
+				`brigade_job_duration_seconds 340`,
+				getUnixTimeMetric(`brigade_job_create_time_seconds`, t2),
+			},
+		},
 		{
 			name:     "A timeout in subcollectors should return bad collector success.",
 			projects: testProjects,
@@ -211,6 +402,7 @@ func TestExporter(t *testing.T) {
 			},
 			expMetrics: []string{
 				// Exporter metrics.
+				`brigade_up 0`,
 				`brigade_exporter_collector_success{collector="projects"} 0`,
 				`brigade_exporter_collector_success{collector="builds"} 0`,
 				`brigade_exporter_collector_success{collector="jobs"} 0`,
@@ -259,7 +451,7 @@ func TestExporter(t *testing.T) {
 			// Mocks.
 			mbsvc := &mbrigade.Interface{}
 			mbsvc.On("GetProjects").Once().Return(test.projects, nil)
-			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+			mbsvc.On("GetBuilds").Return(test.builds, nil)
 			mbsvc.On("GetJobs").Once().Return(test.jobs, nil)
 
 			// Create the exporter.
@@ -292,6 +484,211 @@ func TestExporter(t *testing.T) {
 	}
 }
 
+func TestExporterReady(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, the first time Brigade is not reachable.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Once().Return(nil, fmt.Errorf("wanted error"))
+	mbsvc.On("GetProjects").Once().Return(testProjects, nil)
+	mbsvc.On("GetBuilds").Once().Return(testBuilds, nil)
+
+	// Create the exporter.
+	cfg := collector.Config{
+		DisableBuilds: true,
+		DisableJobs:   true,
+	}
+	clr := collector.NewExporter(cfg, mbsvc, log.Dummy)
+	promReg := prometheus.NewRegistry()
+	promReg.MustRegister(clr)
+	h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
+	assert.False(clr.Ready())
+
+	expUp := []string{`brigade_up 0`, `brigade_up 1`}
+	expReady := []bool{false, true}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		h.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if assert.Equal(http.StatusOK, resp.StatusCode) {
+			body, _ := ioutil.ReadAll(resp.Body)
+			assert.Contains(string(body), expUp[i])
+		}
+		assert.Equal(expReady[i], clr.Ready())
+	}
+}
+
+func TestExporterSubcollectorIntervals(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, the projects are only gathered once because they are cached.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Once().Return(testProjects, nil)
+	mbsvc.On("GetBuilds").Return(testBuilds, nil)
+
+	// Create the exporter.
+	cfg := collector.Config{
+		DisableJobs: true,
+		SubcollectorIntervals: map[string]time.Duration{
+			"projects": time.Hour,
+		},
+	}
+	clr := collector.NewExporter(cfg, mbsvc, log.Dummy)
+	promReg := prometheus.NewRegistry()
+	promReg.MustRegister(clr)
+	h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
+
+	// Make the requests to ask for metrics.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		h.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if assert.Equal(http.StatusOK, resp.StatusCode) {
+			body, _ := ioutil.ReadAll(resp.Body)
+			assert.Contains(string(body), `brigade_project_info{id="id1",name="Name1",namespace="ns1",repository="repo1",worker="worker1"} 1`)
+			assert.Contains(string(body), `brigade_exporter_collector_success{collector="projects"} 1`)
+			assert.Contains(string(body), `brigade_exporter_collector_cache_age_seconds{collector="projects"}`)
+			assert.NotContains(string(body), `brigade_exporter_collector_cache_age_seconds{collector="builds"}`)
+		}
+	}
+
+	mbsvc.AssertExpectations(t)
+}
+
+func TestExporterFiltered(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, the projects shouldn't be gathered.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Return(testBuilds, nil)
+	mbsvc.On("GetJobs").Return(testJobs, nil)
+
+	// Create the exporter.
+	clr := collector.NewExporter(collector.Config{}, mbsvc, log.Dummy)
+	assert.Equal([]string{"builds", "jobs", "projects"}, clr.Subcollectors())
+
+	_, err := clr.Filtered([]string{"builds", "dora"})
+	assert.Error(err)
+
+	fclr, err := clr.Filtered([]string{"builds", "jobs"})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]string{"builds", "jobs"}, fclr.Subcollectors())
+
+	promReg := prometheus.NewRegistry()
+	promReg.MustRegister(fclr)
+	h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	h.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	if assert.Equal(http.StatusOK, resp.StatusCode) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Contains(string(body), `brigade_up 1`)
+		assert.Contains(string(body), `brigade_exporter_collector_success{collector="builds"} 1`)
+		assert.Contains(string(body), `brigade_exporter_collector_success{collector="jobs"} 1`)
+		assert.NotContains(string(body), `brigade_exporter_collector_success{collector="projects"}`)
+		assert.NotContains(string(body), `brigade_project_info`)
+	}
+
+	// The state is shared with the unfiltered exporter.
+	assert.True(clr.Ready())
+	mbsvc.AssertNotCalled(t, "GetProjects")
+}
+
+func TestExporterSubcollectorStatuses(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Return(testBuilds, nil)
+	mbsvc.On("GetJobs").Return(nil, fmt.Errorf("wanted error"))
+
+	// Create the exporter.
+	clr := collector.NewExporter(collector.Config{DisableProjects: true}, mbsvc, log.Dummy)
+	assert.Equal([]collector.SubcollectorStatus{{Name: "builds"}, {Name: "jobs"}}, clr.SubcollectorStatuses())
+
+	promReg := prometheus.NewRegistry()
+	promReg.MustRegister(clr)
+	promReg.Gather()
+
+	statuses := clr.SubcollectorStatuses()
+	if assert.Len(statuses, 2) {
+		assert.Equal("builds", statuses[0].Name)
+		assert.True(statuses[0].Success)
+		assert.Empty(statuses[0].Error)
+		assert.False(statuses[0].LastCollection.IsZero())
+
+		assert.Equal("jobs", statuses[1].Name)
+		assert.False(statuses[1].Success)
+		assert.Equal("wanted error", statuses[1].Error)
+		assert.False(statuses[1].LastCollection.IsZero())
+	}
+}
+
+func TestExporterReload(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	builds := []*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "prj1", Type: "deploy", Version: "c1", Status: "Succeeded", Start: now.Add(-time.Hour), End: now.Add(-50 * time.Minute)},
+		&brigade.Build{ID: "bld2", ProjectID: "prj1", Type: "push", Version: "c1", Status: "Failed", Start: now.Add(-time.Hour), End: now.Add(-50 * time.Minute)},
+	}
+	jobs := []*brigade.Job{
+		&brigade.Job{ID: "id1", BuildID: "bld1", Name: "test", Status: "Succeeded"},
+		&brigade.Job{ID: "id2", BuildID: "bld2", Name: "test", Status: "Failed"},
+	}
+
+	// Mocks, after the reload Brigade doesn't have the builds anymore.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Once().Return(builds, nil)
+	mbsvc.On("GetBuilds").Once().Return(builds, nil)
+	mbsvc.On("GetJobs").Once().Return(jobs, nil)
+	mbsvc.On("GetBuilds").Return(nil, nil)
+	mbsvc.On("GetJobs").Return(nil, nil)
+
+	cfg := collector.Config{
+		DisableProjects: true,
+		DisableBuilds:   true,
+		DisableJobs:     true,
+		EnableFlakyJobs: true,
+		EnableDORA:      true,
+	}
+	clr := collector.NewExporter(cfg, mbsvc, log.Dummy)
+	scrape := func(clr *collector.Exporter) string {
+		promReg := prometheus.NewRegistry()
+		promReg.MustRegister(clr)
+		rec := httptest.NewRecorder()
+		promhttp.HandlerFor(promReg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	body := scrape(clr)
+	assert.Contains(body, `brigade_job_flaky_total{job_name="test",project_id="prj1"} 1`)
+	assert.Contains(body, `brigade_dora_deployments_total{project_id="prj1",status="Succeeded"} 1`)
+
+	// The same configuration keeps the same exporter.
+	assert.True(clr == clr.Reload(cfg, mbsvc, log.Dummy))
+
+	// A new configuration recreates the subcollectors with the state.
+	cfg.Metrics.ConstLabels = map[string]string{"cluster": "prod"}
+	rclr := clr.Reload(cfg, mbsvc, log.Dummy)
+	assert.False(clr == rclr)
+	assert.True(rclr.Ready())
+
+	body = scrape(rclr)
+	assert.Contains(body, `brigade_job_flaky_total{cluster="prod",job_name="test",project_id="prj1"} 1`)
+	assert.Contains(body, `brigade_job_flakiness_ratio{cluster="prod",job_name="test",project_id="prj1"} 1`)
+	assert.Contains(body, `brigade_dora_deployments_total{cluster="prod",project_id="prj1",status="Succeeded"} 1`)
+}
+
 func getUnixTimeMetric(metric string, t time.Time) string {
 	return fmt.Sprintf(`%s %g`, metric, float64(t.Unix()))
 }
@@ -0,0 +1,360 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+// MetricsConfig is the configuration of the metric names and labels
+// of the collectors.
+type MetricsConfig struct {
+	// Namespace is the namespace of the metrics, by default brigade.
+	Namespace string
+	// Prefix is an optional prefix that will be added to the metric names.
+	Prefix string
+	// ConstLabels are labels with constant values that will be added to all the metrics.
+	ConstLabels map[string]string
+	// RenameLabels are the labels that will be renamed, the key is the original
+	// label name and the value the new label name.
+	RenameLabels map[string]string
+	// DropLabels are the original names of the labels that will be dropped.
+	DropLabels []string
+	// AllowedLabels are the original names of the labels that will be kept by metric
+	// name, the rest of the labels of the metric will be dropped.
+	AllowedLabels map[string][]string
+
+	// descErrs collects the label errors of the descriptions when validating.
+	descErrs *descErrors
+}
+
+// descErrors are the errors of the metric descriptions.
+type descErrors struct {
+	mu   sync.Mutex
+	errs []error
+	// allowListedMetrics are the metrics with allowed labels that have been created.
+	allowListedMetrics map[string]bool
+}
+
+func (d *descErrors) allowListed(fqName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.allowListedMetrics == nil {
+		d.allowListedMetrics = map[string]bool{}
+	}
+	d.allowListedMetrics[fqName] = true
+}
+
+func (d *descErrors) add(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errs = append(d.errs, err)
+}
+
+// err returns the first error.
+func (d *descErrors) err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.errs) == 0 {
+		return nil
+	}
+	return d.errs[0]
+}
+
+// Validate will check the metrics configuration is valid.
+func (c MetricsConfig) Validate() error {
+	if c.Namespace != "" && !model.IsValidMetricName(model.LabelValue(c.Namespace)) {
+		return fmt.Errorf("invalid metrics namespace %q", c.Namespace)
+	}
+
+	if c.Prefix != "" && !model.IsValidMetricName(model.LabelValue(c.Prefix)) {
+		return fmt.Errorf("invalid metrics prefix %q", c.Prefix)
+	}
+
+	for name := range c.ConstLabels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid const label name %q", name)
+		}
+	}
+
+	for name := range c.AllowedLabels {
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			return fmt.Errorf("invalid metric name %q on allowed labels", name)
+		}
+	}
+
+	renamed := map[string]bool{}
+	for from, to := range c.RenameLabels {
+		if !model.LabelName(to).IsValid() {
+			return fmt.Errorf("invalid label name %q to rename %q", to, from)
+		}
+		if _, ok := c.ConstLabels[to]; ok {
+			return fmt.Errorf("label %q renamed to %q collides with a const label", from, to)
+		}
+		if renamed[to] {
+			return fmt.Errorf("multiple labels renamed to %q", to)
+		}
+		renamed[to] = true
+	}
+
+	// The final labels of the metrics are only known when creating their descriptions,
+	// create the descriptions of all the Brigade subcollectors to check them.
+	errs := &descErrors{}
+	c.descErrs = errs
+	NewExporter(Config{
+		Metrics:                 c,
+		EnableFlakyJobs:         true,
+		EnableBuildSuccessRatio: true,
+		EnableDORA:              true,
+	}, nil, log.Dummy)
+	if err := errs.err(); err != nil {
+		return err
+	}
+
+	for name := range c.AllowedLabels {
+		if !errs.allowListedMetrics[name] {
+			return fmt.Errorf("unknown %s metric on allowed labels", name)
+		}
+	}
+
+	return nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLabels checks the final labels of a metric don't collide between them
+// or with the const labels.
+func (c MetricsConfig) checkLabels(fqName string, labels []string) error {
+	seen := map[string]bool{}
+	for _, l := range labels {
+		if _, ok := c.ConstLabels[l]; ok {
+			return fmt.Errorf("label %q of %s metric collides with a const label", l, fqName)
+		}
+		if seen[l] {
+			return fmt.Errorf("label %q of %s metric is duplicated, check the renamed labels", l, fqName)
+		}
+		seen[l] = true
+	}
+	return nil
+}
+
+// aggregation is how the values of the metrics that end with the same labels
+// after dropping labels are aggregated.
+type aggregation int
+
+const (
+	// aggregationSum sums the values, for the info metrics and the counts.
+	aggregationSum aggregation = iota
+	// aggregationMax keeps the greatest value, for the timestamps, durations and ratios.
+	aggregationMax
+	// aggregationMin keeps the lowest value, for the time since something happened.
+	aggregationMin
+)
+
+// aggregationOf returns the aggregation of a metric based on the metric naming conventions.
+func aggregationOf(fqName string) aggregation {
+	switch {
+	case strings.Contains(fqName, "_time_since_"):
+		return aggregationMin
+	case strings.HasSuffix(fqName, "_seconds"), strings.HasSuffix(fqName, "_ratio"):
+		return aggregationMax
+	default:
+		return aggregationSum
+	}
+}
+
+// FullNamespace returns the namespace of the metrics with the prefix, the metrics
+// created outside the collectors use it to be named like the collector metrics.
+func (c MetricsConfig) FullNamespace() string {
+	ns := c.Namespace
+	if ns == "" {
+		ns = namespace
+	}
+	return c.Prefix + ns
+}
+
+// metricDesc is a metric description that knows what labels of the
+// metric have been dropped.
+type metricDesc struct {
+	desc *prometheus.Desc
+	// keep are the indexes of the label values that are kept, nil if all of them.
+	keep []int
+	// aggregation is how the metrics with dropped labels are aggregated.
+	aggregation aggregation
+}
+
+// newDesc returns a new metric description applying the metrics configuration
+// to the name and the labels.
+func (c MetricsConfig) newDesc(subsystem, name, help string, labels []string) *metricDesc {
+	fqName := prometheus.BuildFQName(c.FullNamespace(), subsystem, name)
+
+	dropped := map[string]bool{}
+	for _, l := range c.DropLabels {
+		dropped[l] = true
+	}
+
+	// If the metric has an allow list, everything not allowed is dropped.
+	if allowedLabels, ok := c.AllowedLabels[fqName]; ok {
+		if c.descErrs != nil {
+			c.descErrs.allowListed(fqName)
+		}
+
+		allowed := map[string]bool{}
+		for _, l := range allowedLabels {
+			allowed[l] = true
+			if c.descErrs != nil && !contains(labels, l) {
+				c.descErrs.add(fmt.Errorf("allowed label %q is not a label of %s metric", l, fqName))
+			}
+		}
+		for _, l := range labels {
+			if !allowed[l] {
+				dropped[l] = true
+			}
+		}
+	}
+
+	var keep []int
+	finalLabels := make([]string, 0, len(labels))
+	for i, l := range labels {
+		if dropped[l] {
+			continue
+		}
+		keep = append(keep, i)
+
+		if to, ok := c.RenameLabels[l]; ok {
+			l = to
+		}
+		finalLabels = append(finalLabels, l)
+	}
+
+	if c.descErrs != nil {
+		if err := c.checkLabels(fqName, finalLabels); err != nil {
+			c.descErrs.add(err)
+		}
+	}
+
+	// If nothing has been dropped we don't need to filter the label values.
+	if len(keep) == len(labels) {
+		keep = nil
+	} else if keep == nil {
+		keep = []int{}
+	}
+
+	return &metricDesc{
+		desc: prometheus.NewDesc(
+			fqName,
+			help,
+			finalLabels, c.ConstLabels,
+		),
+		keep:        keep,
+		aggregation: aggregationOf(fqName),
+	}
+}
+
+// newConstMetric returns a new constant metric dropping the values of the dropped
+// labels. If the metric can't be created (e.g: a label renamed to an already
+// existing label of the metric) it will return an invalid metric so the error
+// is reported when gathering the metrics.
+func (m *metricDesc) newConstMetric(valueType prometheus.ValueType, value float64, labelValues ...string) prometheus.Metric {
+	if m.keep != nil {
+		lvs := make([]string, len(m.keep))
+		for i, k := range m.keep {
+			lvs[i] = labelValues[k]
+		}
+		labelValues = lvs
+	}
+
+	metric, err := prometheus.NewConstMetric(m.desc, valueType, value, labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(m.desc, err)
+	}
+
+	// Metrics with dropped labels can be duplicated, they need to be aggregated.
+	if m.keep != nil {
+		return &aggregableMetric{
+			Metric:      metric,
+			desc:        m,
+			valueType:   valueType,
+			value:       value,
+			labelValues: labelValues,
+		}
+	}
+
+	return metric
+}
+
+// aggregableMetric is a metric that can be aggregated with the metrics
+// of the same description and label values.
+type aggregableMetric struct {
+	prometheus.Metric
+	desc        *metricDesc
+	valueType   prometheus.ValueType
+	value       float64
+	labelValues []string
+}
+
+// aggregateKey identifies the series of an aggregable metric.
+type aggregateKey struct {
+	desc        *metricDesc
+	labelValues string
+}
+
+// aggregate will aggregate the metrics that have the same description and label
+// values (the ones that had labels dropped) using the aggregation of the metric,
+// the aggregated metrics are returned after the rest of the metrics.
+func aggregate(metrics []prometheus.Metric) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, len(metrics))
+	aggregated := map[aggregateKey]*aggregableMetric{}
+	var keys []aggregateKey
+	for _, m := range metrics {
+		am, ok := m.(*aggregableMetric)
+		if !ok {
+			res = append(res, m)
+			continue
+		}
+
+		key := aggregateKey{desc: am.desc, labelValues: strings.Join(am.labelValues, "\xff")}
+		if agg, ok := aggregated[key]; ok {
+			switch am.desc.aggregation {
+			case aggregationMax:
+				agg.value = math.Max(agg.value, am.value)
+			case aggregationMin:
+				agg.value = math.Min(agg.value, am.value)
+			default:
+				agg.value += am.value
+			}
+			continue
+		}
+		aggregated[key] = &aggregableMetric{
+			desc:        am.desc,
+			valueType:   am.valueType,
+			value:       am.value,
+			labelValues: am.labelValues,
+		}
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		agg := aggregated[key]
+		metric, err := prometheus.NewConstMetric(agg.desc.desc, agg.valueType, agg.value, agg.labelValues...)
+		if err != nil {
+			metric = prometheus.NewInvalidMetric(agg.desc.desc, err)
+		}
+		res = append(res, metric)
+	}
+
+	return res
+}
@@ -0,0 +1,106 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+)
+
+func TestMetricsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    collector.MetricsConfig
+		expErr bool
+	}{
+		{
+			name: "Default configuration should be valid.",
+		},
+		{
+			name: "A complete configuration should be valid.",
+			cfg: collector.MetricsConfig{
+				Namespace:    "ci",
+				Prefix:       "team_",
+				ConstLabels:  map[string]string{"cluster": "prod-1", "environment": "production"},
+				RenameLabels: map[string]string{"version": "commit"},
+				DropLabels:   []string{"image"},
+			},
+		},
+		{
+			name:   "An invalid namespace should fail.",
+			cfg:    collector.MetricsConfig{Namespace: "ci-brigade"},
+			expErr: true,
+		},
+		{
+			name:   "An invalid const label should fail.",
+			cfg:    collector.MetricsConfig{ConstLabels: map[string]string{"k8s-cluster": "prod-1"}},
+			expErr: true,
+		},
+		{
+			name:   "An invalid renamed label should fail.",
+			cfg:    collector.MetricsConfig{RenameLabels: map[string]string{"version": "git-commit"}},
+			expErr: true,
+		},
+		{
+			name: "A renamed label colliding with a const label should fail.",
+			cfg: collector.MetricsConfig{
+				ConstLabels:  map[string]string{"commit": "none"},
+				RenameLabels: map[string]string{"version": "commit"},
+			},
+			expErr: true,
+		},
+		{
+			name:   "Multiple labels renamed to the same label should fail.",
+			cfg:    collector.MetricsConfig{RenameLabels: map[string]string{"version": "x", "image": "x"}},
+			expErr: true,
+		},
+		{
+			name:   "A const label colliding with a metric label should fail.",
+			cfg:    collector.MetricsConfig{ConstLabels: map[string]string{"id": "x"}},
+			expErr: true,
+		},
+		{
+			name:   "A label renamed to another label of the same metric should fail.",
+			cfg:    collector.MetricsConfig{RenameLabels: map[string]string{"version": "id"}},
+			expErr: true,
+		},
+		{
+			name:   "Allowed labels of an unknown metric should fail.",
+			cfg:    collector.MetricsConfig{AllowedLabels: map[string][]string{"brigade_build_unknown": []string{"id"}}},
+			expErr: true,
+		},
+		{
+			name:   "Allowed labels that are not labels of the metric should fail.",
+			cfg:    collector.MetricsConfig{AllowedLabels: map[string][]string{"brigade_build_info": []string{"project"}}},
+			expErr: true,
+		},
+		{
+			name: "Allowed labels of the metric with the configured name should be valid.",
+			cfg: collector.MetricsConfig{
+				Prefix:        "team_",
+				AllowedLabels: map[string][]string{"team_brigade_build_info": []string{"project_id"}},
+			},
+		},
+		{
+			name: "A const label with the name of a dropped label should be valid.",
+			cfg: collector.MetricsConfig{
+				ConstLabels: map[string]string{"image": "x"},
+				DropLabels:  []string{"image"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := test.cfg.Validate()
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
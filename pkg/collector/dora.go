@@ -0,0 +1,336 @@
+package collector
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	doraSubSystem = "dora"
+
+	// Defaults.
+	doraWindowDef = 30 * 24 * time.Hour
+)
+
+var (
+	// Defaults.
+	doraDeployEventTypesDef = []string{"deploy"}
+)
+
+// DORAConfig is the configuration of the DORA subcollector.
+type DORAConfig struct {
+	// DeployEventTypes are the build event types that count as deployments.
+	DeployEventTypes []string
+	// Window is the sliding window used to calculate the DORA metrics.
+	Window time.Duration
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
+// defaults sets the required defaults.
+func (c *DORAConfig) defaults() {
+	if len(c.DeployEventTypes) == 0 {
+		c.DeployEventTypes = doraDeployEventTypesDef
+	}
+
+	if c.Window == 0 {
+		c.Window = doraWindowDef
+	}
+}
+
+// deployment is a finished deployment build.
+type deployment struct {
+	projectID string
+	succeeded bool
+	end       time.Time
+	leadTime  time.Duration
+}
+
+// commitKey identifies a commit of a project.
+type commitKey struct {
+	projectID string
+	version   string
+}
+
+// doraProject are the DORA metrics of a project.
+type doraProject struct {
+	succeeded      float64
+	failed         float64
+	changeFailure  float64
+	leadTimes      []float64
+	timesToRestore []float64
+	inWindow       bool
+}
+
+// dora is the Brigade DORA subcollector. this collector will collect the DORA
+// metrics (deployment frequency, lead time for changes, change failure rate and
+// time to restore) based on the deployment builds of the projects.
+// Satisfies internal collector interface.
+type dora struct {
+	cfg         DORAConfig
+	deployTypes map[string]bool
+	brigadeSVC  brigade.Interface
+	logger      log.Logger
+
+	// State.
+	mu          sync.Mutex
+	commits     map[commitKey]time.Time
+	deployments map[string]*deployment
+	totals      map[string]*doraProject
+
+	// Metrics.
+	deploymentsDesc        *metricDesc
+	changeFailureRatioDesc *metricDesc
+	leadTimeDesc           *metricDesc
+	timeToRestoreDesc      *metricDesc
+}
+
+// NewDORA returns a new DORA subcollector.
+func NewDORA(cfg DORAConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
+	cfg.defaults()
+
+	deployTypes := map[string]bool{}
+	for _, t := range cfg.DeployEventTypes {
+		deployTypes[t] = true
+	}
+
+	return &dora{
+		cfg:         cfg,
+		deployTypes: deployTypes,
+		brigadeSVC:  brigadeSVC,
+		logger:      logger,
+
+		commits:     map[commitKey]time.Time{},
+		deployments: map[string]*deployment{},
+		totals:      map[string]*doraProject{},
+
+		deploymentsDesc: cfg.Metrics.newDesc(
+			doraSubSystem, "deployments_total",
+			"Number of finished Brigade deployment builds.",
+			[]string{"project_id", "status"},
+		),
+		changeFailureRatioDesc: cfg.Metrics.newDesc(
+			doraSubSystem, "change_failure_ratio",
+			"Ratio of the finished Brigade deployment builds that failed in the window.",
+			[]string{"project_id"},
+		),
+		leadTimeDesc: cfg.Metrics.newDesc(
+			doraSubSystem, "lead_time_seconds",
+			"Median time from the first build of a commit to its successful deployment in the window.",
+			[]string{"project_id"},
+		),
+		timeToRestoreDesc: cfg.Metrics.newDesc(
+			doraSubSystem, "time_to_restore_seconds",
+			"Median time from a failed deployment to the next successful deployment in the window.",
+			[]string{"project_id"},
+		),
+	}
+}
+
+// inherit satisfies stateful, the deployments are only kept if the deployment
+// event types didn't change, otherwise they would be counted with other types.
+func (d *dora) inherit(prev Subcollector) {
+	p, ok := prev.(*dora)
+	if !ok || !reflect.DeepEqual(p.deployTypes, d.deployTypes) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, first := range p.commits {
+		d.commits[key] = first
+	}
+	for id, dep := range p.deployments {
+		dp := *dep
+		d.deployments[id] = &dp
+	}
+	for id, total := range p.totals {
+		d.totals[id] = &doraProject{succeeded: total.succeeded, failed: total.failed}
+	}
+}
+
+// Collect satisfies Subcollector.
+func (d *dora) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	blds, err := d.brigadeSVC.GetBuilds()
+	if err != nil {
+		return err
+	}
+
+	prjs := d.track(blds, time.Now())
+
+	// Sort so the metrics are always sent in the same order.
+	ids := make([]string, 0, len(prjs))
+	for id := range prjs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		prj := prjs[id]
+
+		metrics := []prometheus.Metric{
+			d.deploymentsDesc.newConstMetric(prometheus.CounterValue, prj.succeeded, id, brigade.StatusSucceeded),
+			d.deploymentsDesc.newConstMetric(prometheus.CounterValue, prj.failed, id, brigade.StatusFailed),
+		}
+		if prj.inWindow {
+			metrics = append(metrics, d.changeFailureRatioDesc.newConstMetric(prometheus.GaugeValue, prj.changeFailure, id))
+		}
+		if len(prj.leadTimes) > 0 {
+			metrics = append(metrics, d.leadTimeDesc.newConstMetric(prometheus.GaugeValue, median(prj.leadTimes), id))
+		}
+		if len(prj.timesToRestore) > 0 {
+			metrics = append(metrics, d.timeToRestoreDesc.newConstMetric(prometheus.GaugeValue, median(prj.timesToRestore), id))
+		}
+
+		for _, m := range metrics {
+			if err := sendMetric(ctx, ch, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// track will update the state with the builds and return the DORA metrics of
+// every project that had deployments.
+func (d *dora) track(blds []*brigade.Build, now time.Time) map[string]*doraProject {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Track the first time we know about a commit, this is the start of the lead time.
+	for _, bld := range blds {
+		if bld.Version == "" || bld.Start.IsZero() || now.Sub(bld.Start) > d.cfg.Window {
+			continue
+		}
+
+		key := commitKey{projectID: bld.ProjectID, version: bld.Version}
+		if first, ok := d.commits[key]; !ok || bld.Start.Before(first) {
+			d.commits[key] = bld.Start
+		}
+	}
+
+	// Track the new finished deployments.
+	for _, bld := range blds {
+		if !d.deployTypes[bld.Type] {
+			continue
+		}
+		if bld.Status != brigade.StatusSucceeded && bld.Status != brigade.StatusFailed {
+			continue
+		}
+		if _, ok := d.deployments[bld.ID]; ok {
+			continue
+		}
+
+		// Ignore the deployments out of the window, this way the already forgotten
+		// deployments will not be tracked again.
+		end := bld.End
+		if end.IsZero() {
+			end = now
+		}
+		if now.Sub(end) > d.cfg.Window {
+			continue
+		}
+
+		dep := &deployment{
+			projectID: bld.ProjectID,
+			succeeded: bld.Status == brigade.StatusSucceeded,
+			end:       end,
+		}
+		if first, ok := d.commits[commitKey{projectID: bld.ProjectID, version: bld.Version}]; ok && dep.succeeded {
+			dep.leadTime = end.Sub(first)
+		}
+		d.deployments[bld.ID] = dep
+
+		prj, ok := d.totals[bld.ProjectID]
+		if !ok {
+			prj = &doraProject{}
+			d.totals[bld.ProjectID] = prj
+		}
+		if dep.succeeded {
+			prj.succeeded++
+		} else {
+			prj.failed++
+		}
+	}
+
+	// Forget everything out of the window.
+	for key, first := range d.commits {
+		if now.Sub(first) > d.cfg.Window {
+			delete(d.commits, key)
+		}
+	}
+	byProject := map[string][]*deployment{}
+	for id, dep := range d.deployments {
+		if now.Sub(dep.end) > d.cfg.Window {
+			delete(d.deployments, id)
+			continue
+		}
+		byProject[dep.projectID] = append(byProject[dep.projectID], dep)
+	}
+
+	// Calculate the metrics of the projects.
+	res := make(map[string]*doraProject, len(d.totals))
+	for id, total := range d.totals {
+		prj := &doraProject{
+			succeeded: total.succeeded,
+			failed:    total.failed,
+		}
+		res[id] = prj
+
+		deps := byProject[id]
+		if len(deps) == 0 {
+			continue
+		}
+		sort.Slice(deps, func(i, j int) bool { return deps[i].end.Before(deps[j].end) })
+
+		var failed float64
+		var failedSince time.Time
+		for _, dep := range deps {
+			if !dep.succeeded {
+				failed++
+				// Restore time starts on the first failure.
+				if failedSince.IsZero() {
+					failedSince = dep.end
+				}
+				continue
+			}
+
+			if dep.leadTime > 0 {
+				prj.leadTimes = append(prj.leadTimes, dep.leadTime.Seconds())
+			}
+			if !failedSince.IsZero() {
+				prj.timesToRestore = append(prj.timesToRestore, dep.end.Sub(failedSince).Seconds())
+				failedSince = time.Time{}
+			}
+		}
+		prj.inWindow = true
+		prj.changeFailure = failed / float64(len(deps))
+	}
+
+	return res
+}
+
+// median returns the median of the values.
+func median(values []float64) float64 {
+	vs := make([]float64, len(values))
+	copy(vs, values)
+	sort.Float64s(vs)
+
+	l := len(vs)
+	if l%2 == 0 {
+		return (vs[l/2-1] + vs[l/2]) / 2
+	}
+	return vs[l/2]
+}
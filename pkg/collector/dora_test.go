@@ -0,0 +1,145 @@
+package collector_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	doraDeploymentsDesc        = `Desc{fqName: "brigade_dora_deployments_total", help: "Number of finished Brigade deployment builds.", constLabels: {}, variableLabels: [project_id status]}`
+	doraChangeFailureRatioDesc = `Desc{fqName: "brigade_dora_change_failure_ratio", help: "Ratio of the finished Brigade deployment builds that failed in the window.", constLabels: {}, variableLabels: [project_id]}`
+	doraLeadTimeDesc           = `Desc{fqName: "brigade_dora_lead_time_seconds", help: "Median time from the first build of a commit to its successful deployment in the window.", constLabels: {}, variableLabels: [project_id]}`
+	doraTimeToRestoreDesc      = `Desc{fqName: "brigade_dora_time_to_restore_seconds", help: "Median time from a failed deployment to the next successful deployment in the window.", constLabels: {}, variableLabels: [project_id]}`
+)
+
+func TestDORASubcollector(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	tests := []struct {
+		name       string
+		cfg        collector.DORAConfig
+		builds     []*brigade.Build
+		expMetrics []metricResult
+	}{
+		{
+			name: "The DORA metrics should be calculated from the deployment builds.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", ProjectID: "prj1", Type: "push", Version: "c1", Status: "Succeeded", Start: ago(5 * time.Hour), End: ago(290 * time.Minute)},
+				&brigade.Build{ID: "id2", ProjectID: "prj1", Type: "deploy", Version: "c1", Status: "Succeeded", Start: ago(4 * time.Hour), End: ago(230 * time.Minute)},
+				&brigade.Build{ID: "id3", ProjectID: "prj1", Type: "deploy", Version: "c2", Status: "Failed", Start: ago(3 * time.Hour), End: ago(175 * time.Minute)},
+				&brigade.Build{ID: "id4", ProjectID: "prj1", Type: "deploy", Version: "c3", Status: "Failed", Start: ago(160 * time.Minute), End: ago(150 * time.Minute)},
+				&brigade.Build{ID: "id5", ProjectID: "prj1", Type: "deploy", Version: "c3", Status: "Succeeded", Start: ago(2 * time.Hour), End: ago(115 * time.Minute)},
+				&brigade.Build{ID: "id6", ProjectID: "prj1", Type: "deploy", Version: "c4", Status: "Running", Start: ago(time.Minute)},
+				&brigade.Build{ID: "id7", ProjectID: "prj2", Type: "deploy_post_hook", Version: "c1", Status: "Succeeded", Start: ago(time.Hour), End: ago(50 * time.Minute)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       doraDeploymentsDesc,
+					labels:     labelMap{"project_id": "prj1", "status": "Succeeded"},
+					value:      2,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       doraDeploymentsDesc,
+					labels:     labelMap{"project_id": "prj1", "status": "Failed"},
+					value:      2,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       doraChangeFailureRatioDesc,
+					labels:     labelMap{"project_id": "prj1"},
+					value:      0.5,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       doraLeadTimeDesc,
+					labels:     labelMap{"project_id": "prj1"},
+					value:      3450, // Median of 70m and 45m.
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       doraTimeToRestoreDesc,
+					labels:     labelMap{"project_id": "prj1"},
+					value:      3600,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "The deployment event types should be configurable.",
+			cfg: collector.DORAConfig{
+				DeployEventTypes: []string{"deploy_post_hook"},
+			},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", ProjectID: "prj1", Type: "deploy", Version: "c1", Status: "Succeeded", Start: ago(4 * time.Hour), End: ago(230 * time.Minute)},
+				&brigade.Build{ID: "id2", ProjectID: "prj2", Type: "deploy_post_hook", Version: "c1", Status: "Succeeded", Start: ago(time.Hour), End: ago(50 * time.Minute)},
+				&brigade.Build{ID: "id3", ProjectID: "prj2", Type: "deploy_post_hook", Version: "c2", Status: "Succeeded", Start: ago(90 * 24 * time.Hour), End: ago(90 * 24 * time.Hour)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       doraDeploymentsDesc,
+					labels:     labelMap{"project_id": "prj2", "status": "Succeeded"},
+					value:      1,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       doraDeploymentsDesc,
+					labels:     labelMap{"project_id": "prj2", "status": "Failed"},
+					value:      0,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       doraChangeFailureRatioDesc,
+					labels:     labelMap{"project_id": "prj2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       doraLeadTimeDesc,
+					labels:     labelMap{"project_id": "prj2"},
+					value:      600,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+
+			clr := collector.NewDORA(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get the metrics
+			var got []metricResult
+			for m := range ch {
+				got = append(got, readMetric(m))
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package collector
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	// Defaults.
+	flakyJobWindowDef = 24 * time.Hour
+)
+
+// FlakyJobConfig is the configuration of the flaky job subcollector.
+type FlakyJobConfig struct {
+	// Window is the sliding window used to track the job runs and
+	// calculate the flakiness ratio.
+	Window time.Duration
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
+// defaults sets the required defaults.
+func (c *FlakyJobConfig) defaults() {
+	if c.Window == 0 {
+		c.Window = flakyJobWindowDef
+	}
+}
+
+// flakyJobKey identifies a job of a project.
+type flakyJobKey struct {
+	projectID string
+	jobName   string
+}
+
+// flakyJobRunKey identifies the runs of a job in the builds of the
+// same project commit.
+type flakyJobRunKey struct {
+	flakyJobKey
+	version string
+}
+
+// flakyJobRun is the result of all the runs of the same job in the builds
+// of the same project commit.
+type flakyJobRun struct {
+	succeeded bool
+	failed    bool
+	last      time.Time
+}
+
+// flaky returns true if the same job in the same commit has succeeded
+// and failed.
+func (f *flakyJobRun) flaky() bool {
+	return f.succeeded && f.failed
+}
+
+// flakyJob is the Brigade flaky job subcollector. this collector will correlate
+// the builds by project and commit, and the jobs by name, a job that has
+// succeeded and failed on the same commit is a flaky job.
+// Satisfies internal collector interface.
+type flakyJob struct {
+	cfg        FlakyJobConfig
+	brigadeSVC brigade.Interface
+	logger     log.Logger
+
+	// State.
+	mu     sync.Mutex
+	runs   map[flakyJobRunKey]*flakyJobRun
+	flakes map[flakyJobKey]float64
+	// stamped are the times given to the jobs without creation when they were tracked,
+	// kept while Brigade has the jobs so they are not tracked again with a new time
+	// once they are out of the window.
+	stamped map[string]time.Time
+
+	// Metrics.
+	jobFlakyDesc      *metricDesc
+	jobFlakyRatioDesc *metricDesc
+}
+
+// NewFlakyJob returns a new flaky job subcollector.
+func NewFlakyJob(cfg FlakyJobConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
+	cfg.defaults()
+
+	return &flakyJob{
+		cfg:        cfg,
+		brigadeSVC: brigadeSVC,
+		logger:     logger,
+
+		runs:    map[flakyJobRunKey]*flakyJobRun{},
+		flakes:  map[flakyJobKey]float64{},
+		stamped: map[string]time.Time{},
+
+		jobFlakyDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "flaky_total",
+			"Number of project commits where the same job has succeeded and failed.",
+			[]string{"project_id", "job_name"},
+		),
+		jobFlakyRatioDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "flakiness_ratio",
+			"Ratio of the project commits in the sliding window where the job has been flaky.",
+			[]string{"project_id", "job_name"},
+		),
+	}
+}
+
+// inherit satisfies stateful, the job runs and the flaky counters are kept.
+func (f *flakyJob) inherit(prev Subcollector) {
+	p, ok := prev.(*flakyJob)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, run := range p.runs {
+		r := *run
+		f.runs[key] = &r
+	}
+	for key, flakes := range p.flakes {
+		f.flakes[key] = flakes
+	}
+	for id, t := range p.stamped {
+		f.stamped[id] = t
+	}
+}
+
+// Collect satisfies Subcollector.
+func (f *flakyJob) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	blds, err := f.brigadeSVC.GetBuilds()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := f.brigadeSVC.GetJobs()
+	if err != nil {
+		return err
+	}
+
+	totals, ratios := f.track(blds, jobs, time.Now())
+
+	// Sort so the metrics are always sent in the same order.
+	keys := make([]flakyJobKey, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].projectID != keys[j].projectID {
+			return keys[i].projectID < keys[j].projectID
+		}
+		return keys[i].jobName < keys[j].jobName
+	})
+
+	for _, k := range keys {
+		err := sendMetric(ctx, ch, f.jobFlakyDesc.newConstMetric(
+			prometheus.CounterValue,
+			totals[k],
+			k.projectID, k.jobName))
+
+		if err != nil {
+			return err
+		}
+
+		ratio, ok := ratios[k]
+		if !ok {
+			continue
+		}
+
+		err = sendMetric(ctx, ch, f.jobFlakyRatioDesc.newConstMetric(
+			prometheus.GaugeValue,
+			ratio,
+			k.projectID, k.jobName))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// track will update the state with the finished jobs of the builds and return
+// the total flakes and the flakiness ratio of the jobs inside the window.
+func (f *flakyJob) track(blds []*brigade.Build, jobs []*brigade.Job, now time.Time) (totals, ratios map[flakyJobKey]float64) {
+	bldsByID := make(map[string]*brigade.Build, len(blds))
+	for _, bld := range blds {
+		bldsByID[bld.ID] = bld
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	present := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		present[job.ID] = true
+		if job.Status != brigade.StatusSucceeded && job.Status != brigade.StatusFailed {
+			continue
+		}
+
+		// Without the commit we can't correlate the job with other builds.
+		bld, ok := bldsByID[job.BuildID]
+		if !ok || bld.Version == "" {
+			continue
+		}
+
+		// Ignore the runs out of the window, this way the already forgotten
+		// runs will not be tracked again. The jobs without creation were created
+		// when they were tracked the first time.
+		t := job.Creation
+		if t.IsZero() {
+			stamped, ok := f.stamped[job.ID]
+			if !ok {
+				stamped = now
+				f.stamped[job.ID] = stamped
+			}
+			t = stamped
+		}
+		if now.Sub(t) > f.cfg.Window {
+			continue
+		}
+
+		key := flakyJobRunKey{
+			flakyJobKey: flakyJobKey{projectID: bld.ProjectID, jobName: job.Name},
+			version:     bld.Version,
+		}
+		run, ok := f.runs[key]
+		if !ok {
+			run = &flakyJobRun{}
+			f.runs[key] = run
+		}
+		if t.After(run.last) {
+			run.last = t
+		}
+
+		// Count the flake only once, when it transitions to flaky.
+		wasFlaky := run.flaky()
+		if job.Status == brigade.StatusSucceeded {
+			run.succeeded = true
+		} else {
+			run.failed = true
+		}
+		if !wasFlaky && run.flaky() {
+			f.flakes[key.flakyJobKey]++
+		}
+	}
+
+	for id := range f.stamped {
+		if !present[id] {
+			delete(f.stamped, id)
+		}
+	}
+
+	// Forget the runs out of the window and calculate the ratios.
+	flakyRuns := map[flakyJobKey]float64{}
+	allRuns := map[flakyJobKey]float64{}
+	for key, run := range f.runs {
+		if now.Sub(run.last) > f.cfg.Window {
+			delete(f.runs, key)
+			continue
+		}
+
+		allRuns[key.flakyJobKey]++
+		if run.flaky() {
+			flakyRuns[key.flakyJobKey]++
+		}
+	}
+
+	totals = make(map[flakyJobKey]float64, len(f.flakes)+len(allRuns))
+	for k, v := range f.flakes {
+		totals[k] = v
+	}
+	ratios = make(map[flakyJobKey]float64, len(allRuns))
+	for k, v := range allRuns {
+		// The jobs without flakes have also the counter.
+		if _, ok := totals[k]; !ok {
+			totals[k] = 0
+		}
+		ratios[k] = flakyRuns[k] / v
+	}
+
+	return totals, ratios
+}
@@ -0,0 +1,245 @@
+package collector_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	jobFlakyDesc      = `Desc{fqName: "brigade_job_flaky_total", help: "Number of project commits where the same job has succeeded and failed.", constLabels: {}, variableLabels: [project_id job_name]}`
+	jobFlakyRatioDesc = `Desc{fqName: "brigade_job_flakiness_ratio", help: "Ratio of the project commits in the sliding window where the job has been flaky.", constLabels: {}, variableLabels: [project_id job_name]}`
+)
+
+func TestFlakyJobSubcollector(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+
+	tests := []struct {
+		name       string
+		builds     []*brigade.Build
+		jobs       []*brigade.Job
+		expMetrics []metricResult
+	}{
+		{
+			name: "A job that succeeded and failed on the same commit should be flaky.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "bld1", ProjectID: "prj1", Version: "c1"},
+				&brigade.Build{ID: "bld2", ProjectID: "prj1", Version: "c1"},
+				&brigade.Build{ID: "bld3", ProjectID: "prj1", Version: "c2"},
+			},
+			jobs: []*brigade.Job{
+				&brigade.Job{ID: "id1", BuildID: "bld1", Name: "test", Status: "Succeeded"},
+				&brigade.Job{ID: "id2", BuildID: "bld2", Name: "test", Status: "Failed"},
+				&brigade.Job{ID: "id3", BuildID: "bld3", Name: "test", Status: "Succeeded"},
+				&brigade.Job{ID: "id4", BuildID: "bld1", Name: "lint", Status: "Succeeded"},
+				&brigade.Job{ID: "id5", BuildID: "bld2", Name: "lint", Status: "Succeeded"},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       jobFlakyDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "lint"},
+					value:      0,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       jobFlakyRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "lint"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobFlakyDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+					value:      1,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       jobFlakyRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+					value:      0.5,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "Jobs of different projects, not finished or out of the window should not be correlated.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "bld1", ProjectID: "prj1", Version: "c1"},
+				&brigade.Build{ID: "bld2", ProjectID: "prj2", Version: "c1"},
+				&brigade.Build{ID: "bld3", ProjectID: "prj1", Version: "c1"},
+				&brigade.Build{ID: "bld4", ProjectID: "prj1", Version: "c1"},
+			},
+			jobs: []*brigade.Job{
+				&brigade.Job{ID: "id1", BuildID: "bld1", Name: "test", Status: "Succeeded"},
+				&brigade.Job{ID: "id2", BuildID: "bld2", Name: "test", Status: "Failed"},
+				&brigade.Job{ID: "id3", BuildID: "bld3", Name: "test", Status: "Running"},
+				&brigade.Job{ID: "id4", BuildID: "bld4", Name: "test", Status: "Failed", Creation: old},
+				&brigade.Job{ID: "id5", BuildID: "unknown", Name: "test", Status: "Failed"},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       jobFlakyDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+					value:      0,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       jobFlakyRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobFlakyDesc,
+					labels:     labelMap{"project_id": "prj2", "job_name": "test"},
+					value:      0,
+					metricType: dto.MetricType_COUNTER,
+				},
+				metricResult{
+					desc:       jobFlakyRatioDesc,
+					labels:     labelMap{"project_id": "prj2", "job_name": "test"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+			mbsvc.On("GetJobs").Once().Return(test.jobs, nil)
+
+			clr := collector.NewFlakyJob(collector.FlakyJobConfig{}, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get the metrics
+			var got []metricResult
+			for m := range ch {
+				got = append(got, readMetric(m))
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestFlakyJobSubcollectorCountsOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	builds := []*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "prj1", Version: "c1"},
+		&brigade.Build{ID: "bld2", ProjectID: "prj1", Version: "c1"},
+	}
+	jobs := []*brigade.Job{
+		&brigade.Job{ID: "id1", BuildID: "bld1", Name: "test", Status: "Succeeded"},
+		&brigade.Job{ID: "id2", BuildID: "bld2", Name: "test", Status: "Failed"},
+	}
+
+	// Mocks.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Return(builds, nil)
+	mbsvc.On("GetJobs").Return(jobs, nil)
+
+	clr := collector.NewFlakyJob(collector.FlakyJobConfig{}, mbsvc, log.Dummy)
+
+	// Collect multiple times the same data, the flake should be counted only once.
+	var got []metricResult
+	for i := 0; i < 3; i++ {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			clr.Collect(context.TODO(), ch)
+			close(ch)
+		}()
+
+		got = got[:0]
+		for m := range ch {
+			got = append(got, readMetric(m))
+		}
+	}
+
+	exp := []metricResult{
+		metricResult{
+			desc:       jobFlakyDesc,
+			labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+			value:      1,
+			metricType: dto.MetricType_COUNTER,
+		},
+		metricResult{
+			desc:       jobFlakyRatioDesc,
+			labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+			value:      1,
+			metricType: dto.MetricType_GAUGE,
+		},
+	}
+	assert.Equal(exp, got)
+}
+
+func TestFlakyJobSubcollectorJobsWithoutCreation(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, the jobs without creation are always returned by Brigade.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Return([]*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "prj1", Version: "c1"},
+		&brigade.Build{ID: "bld2", ProjectID: "prj1", Version: "c1"},
+	}, nil)
+	mbsvc.On("GetJobs").Return([]*brigade.Job{
+		&brigade.Job{ID: "id1", BuildID: "bld1", Name: "test", Status: "Succeeded"},
+		&brigade.Job{ID: "id2", BuildID: "bld2", Name: "test", Status: "Failed"},
+	}, nil)
+
+	window := 50 * time.Millisecond
+	clr := collector.NewFlakyJob(collector.FlakyJobConfig{Window: window}, mbsvc, log.Dummy)
+	collect := func() []metricResult {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			clr.Collect(context.TODO(), ch)
+			close(ch)
+		}()
+
+		var got []metricResult
+		for m := range ch {
+			got = append(got, readMetric(m))
+		}
+		return got
+	}
+
+	// The jobs were created when they were tracked the first time, so the run is
+	// out of the window after the window passes even if it's collected again.
+	assert.Len(collect(), 2)
+	for i := 0; i < 3; i++ {
+		time.Sleep(window / 2)
+		collect()
+	}
+
+	exp := []metricResult{
+		metricResult{
+			desc:       jobFlakyDesc,
+			labels:     labelMap{"project_id": "prj1", "job_name": "test"},
+			value:      1,
+			metricType: dto.MetricType_COUNTER,
+		},
+	}
+	assert.Equal(exp, collect())
+}
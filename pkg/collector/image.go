@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultImageRegistry  = "docker.io"
+	defaultImageNamespace = "library"
+	defaultImageTag       = "latest"
+)
+
+// imageRef is a parsed container image reference.
+type imageRef struct {
+	registry   string
+	repository string
+	// tag is the tag of the image or the digest if the image doesn't have tag.
+	tag string
+}
+
+// name returns the image reference without the tag.
+func (i imageRef) name() string {
+	return i.registry + "/" + i.repository
+}
+
+// parseImage parses a container image reference (e.g: quay.io/slok/brigade-exporter:v0.3.0)
+// in registry, repository and tag, it will normalize the reference as Docker does.
+func parseImage(image string) imageRef {
+	name := image
+	digest := ""
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+
+	// The tag is after the last colon only if is after the last slash,
+	// otherwise it's the port of the registry.
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	if tag == "" {
+		tag = digest
+	}
+	if tag == "" {
+		tag = defaultImageTag
+	}
+
+	// The first component is a registry only if it looks like a host.
+	registry := defaultImageRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry, name = first, name[i+1:]
+		}
+	}
+
+	// Official images of Docker Hub.
+	if registry == defaultImageRegistry && !strings.Contains(name, "/") {
+		name = defaultImageNamespace + "/" + name
+	}
+
+	return imageRef{
+		registry:   registry,
+		repository: name,
+		tag:        tag,
+	}
+}
+
+// countImages returns the number of times each image is repeated sorted by
+// registry, repository and tag, empty images are ignored.
+func countImages(images []string) ([]imageRef, map[imageRef]float64) {
+	counts := map[imageRef]float64{}
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		counts[parseImage(image)]++
+	}
+
+	refs := make([]imageRef, 0, len(counts))
+	for ref := range counts {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].registry != refs[j].registry {
+			return refs[i].registry < refs[j].registry
+		}
+		if refs[i].repository != refs[j].repository {
+			return refs[i].repository < refs[j].repository
+		}
+		return refs[i].tag < refs[j].tag
+	})
+
+	return refs, counts
+}
+
+// compareVersions compares two version tags (e.g: v1.2.3 and 1.3.0-rc.1) returning -1, 0 or 1
+// if a is older, equal or newer than b. ok is false if any of the tags is not a version.
+func compareVersions(a, b string) (res int, ok bool) {
+	an, apre, aok := parseVersion(a)
+	bn, bpre, bok := parseVersion(b)
+	if !aok || !bok {
+		return 0, false
+	}
+
+	for i := 0; i < len(an) || i < len(bn); i++ {
+		var av, bv int
+		if i < len(an) {
+			av = an[i]
+		}
+		if i < len(bn) {
+			bv = bn[i]
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		}
+	}
+
+	// The pre-releases are older than the release.
+	switch {
+	case apre == bpre:
+		return 0, true
+	case apre == "":
+		return 1, true
+	case bpre == "":
+		return -1, true
+	case apre < bpre:
+		return -1, true
+	default:
+		return 1, true
+	}
+}
+
+// parseVersion parses a version tag in its numeric components and its pre-release,
+// ok is false if the tag is not a version.
+func parseVersion(tag string) (nums []int, pre string, ok bool) {
+	v := strings.TrimPrefix(tag, "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.Index(v, "-"); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+
+	return nums, pre, true
+}
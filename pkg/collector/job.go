@@ -2,7 +2,6 @@ package collector
 
 import (
 	"context"
-	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -14,66 +13,95 @@ const (
 	jobSubSystem = "job"
 )
 
+// JobConfig is the configuration of the job subcollector.
+type JobConfig struct {
+	// StatusStateSet will send the status metric of every known status for each job
+	// with 0 or 1 values, instead of a single metric with the current status.
+	StatusStateSet bool
+	// MaxSeries is the max number of job series, when exceeded the non terminal and
+	// most recent jobs will be kept and the rest dropped. 0 means no limit.
+	MaxSeries int
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
 // job is the Brigade Job subcollector. this colletor will collect
 // the metrics regarding brigade jobs.
 // Satisfies internfal collector interface.
 type job struct {
+	cfg        JobConfig
 	brigadeSVC brigade.Interface
 	logger     log.Logger
+	*seriesLimit
 
 	// Metrics.
-	jobInfoDesc     *prometheus.Desc
-	jobStatusDesc   *prometheus.Desc
-	jobDurationDesc *prometheus.Desc
-	jobCreationDesc *prometheus.Desc
-	jobStartDesc    *prometheus.Desc
+	jobInfoDesc     *metricDesc
+	jobStatusDesc   *metricDesc
+	jobDurationDesc *metricDesc
+	jobCreationDesc *metricDesc
+	jobStartDesc    *metricDesc
+	jobImagesDesc   *metricDesc
 }
 
 // NewJob returns a new job subcollector.
-func NewJob(brigadeSVC brigade.Interface, logger log.Logger) subcollector {
+func NewJob(cfg JobConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
 	return &job{
-		brigadeSVC: brigadeSVC,
-		logger:     logger,
+		cfg:         cfg,
+		brigadeSVC:  brigadeSVC,
+		logger:      logger,
+		seriesLimit: &seriesLimit{max: cfg.MaxSeries},
 
-		jobInfoDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, jobSubSystem, "info"),
+		jobInfoDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "info",
 			"Brigade job information.",
-			[]string{"id", "build_id", "name", "image"}, nil,
+			[]string{"id", "build_id", "name", "image"},
 		),
-		jobStatusDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, jobSubSystem, "status"),
+		jobStatusDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "status",
 			"Brigade job status.",
-			[]string{"id", "status"}, nil,
+			[]string{"id", "status"},
 		),
-		jobDurationDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, jobSubSystem, "duration_seconds"),
+		jobDurationDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "duration_seconds",
 			"Brigade job duration in seconds.",
-			[]string{"id"}, nil,
+			[]string{"id"},
 		),
-		jobCreationDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, jobSubSystem, "create_time_seconds"),
+		jobCreationDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "create_time_seconds",
 			"Brigade job creation time in unix timestamp.",
-			[]string{"id"}, nil,
+			[]string{"id"},
 		),
-		jobStartDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, jobSubSystem, "start_time_seconds"),
+		jobStartDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "start_time_seconds",
 			"Brigade job start time in unix timestamp.",
-			[]string{"id"}, nil,
+			[]string{"id"},
+		),
+		jobImagesDesc: cfg.Metrics.newDesc(
+			jobSubSystem, "images",
+			"Number of Brigade jobs using the image.",
+			[]string{"registry", "repository", "tag"},
 		),
 	}
 }
 
-// Collect satisfies subcollector.
+// Collect satisfies Subcollector.
 func (j *job) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 	jobs, err := j.brigadeSVC.GetJobs()
 	if err != nil {
 		return err
 	}
 
+	// The images are aggregated so we use all the jobs.
+	images := make([]string, len(jobs))
+	for i, job := range jobs {
+		images[i] = job.Image
+	}
+
+	// Info, duration, creation, start and status series.
+	jobs = j.limitJobs(jobs, 4+statusSeries(j.cfg.StatusStateSet))
 	for _, job := range jobs {
 		// Info metric.
-		err := sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			j.jobInfoDesc,
+		err := sendMetric(ctx, ch, j.jobInfoDesc.newConstMetric(
 			prometheus.GaugeValue,
 			1,
 			job.ID, job.BuildID, job.Name, job.Image))
@@ -83,11 +111,7 @@ func (j *job) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 		}
 
 		// Status metric.
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			j.jobStatusDesc,
-			prometheus.GaugeValue,
-			1,
-			job.ID, job.Status))
+		err = sendStatusMetric(ctx, ch, j.jobStatusDesc, j.cfg.StatusStateSet, job.ID, job.Status)
 
 		if err != nil {
 			return err
@@ -95,8 +119,7 @@ func (j *job) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 
 		// Duration metric.
 		// TODO: Think if it's 0 we should send the metric or not.
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			j.jobDurationDesc,
+		err = sendMetric(ctx, ch, j.jobDurationDesc.newConstMetric(
 			prometheus.GaugeValue,
 			job.Duration.Seconds(),
 			job.ID))
@@ -107,33 +130,35 @@ func (j *job) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 
 		// creation and start metrics.
 		// TODO: Think if it's `time.IsZero`` we should send the metric or not.
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			j.jobCreationDesc,
+		err = sendMetric(ctx, ch, j.jobCreationDesc.newConstMetric(
 			prometheus.GaugeValue,
-			j.getUnix(job.Creation),
+			getUnix(job.Creation),
 			job.ID))
 
 		if err != nil {
 			return err
 		}
 
-		err = sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			j.jobStartDesc,
+		err = sendMetric(ctx, ch, j.jobStartDesc.newConstMetric(
 			prometheus.GaugeValue,
-			j.getUnix(job.Start),
+			getUnix(job.Start),
 			job.ID))
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
-
-func (*job) getUnix(t time.Time) float64 {
-	if t.IsZero() {
-		return 0
+	// Images metrics.
+	refs, counts := countImages(images)
+	for _, ref := range refs {
+		err := sendMetric(ctx, ch, j.jobImagesDesc.newConstMetric(
+			prometheus.GaugeValue,
+			counts[ref],
+			ref.registry, ref.repository, ref.tag))
+		if err != nil {
+			return err
+		}
 	}
 
-	return float64(t.Unix())
+	return nil
 }
@@ -21,6 +21,7 @@ const (
 	jobDurationDesc = `Desc{fqName: "brigade_job_duration_seconds", help: "Brigade job duration in seconds.", constLabels: {}, variableLabels: [id]}`
 	jobCreationDesc = `Desc{fqName: "brigade_job_create_time_seconds", help: "Brigade job creation time in unix timestamp.", constLabels: {}, variableLabels: [id]}`
 	jobStartDesc    = `Desc{fqName: "brigade_job_start_time_seconds", help: "Brigade job start time in unix timestamp.", constLabels: {}, variableLabels: [id]}`
+	jobImagesDesc   = `Desc{fqName: "brigade_job_images", help: "Number of Brigade jobs using the image.", constLabels: {}, variableLabels: [registry repository tag]}`
 )
 
 func TestJobSubcollector(t *testing.T) {
@@ -135,6 +136,25 @@ func TestJobSubcollector(t *testing.T) {
 					value:      float64(t4.Unix()),
 					metricType: dto.MetricType_GAUGE,
 				},
+
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/image1", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/image2", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/image3", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
 			},
 		},
 	}
@@ -147,7 +167,7 @@ func TestJobSubcollector(t *testing.T) {
 			mbsvc := &mbrigade.Interface{}
 			mbsvc.On("GetJobs").Once().Return(test.jobs, nil)
 
-			clr := collector.NewJob(mbsvc, log.Dummy)
+			clr := collector.NewJob(collector.JobConfig{}, mbsvc, log.Dummy)
 
 			ch := make(chan prometheus.Metric)
 
@@ -167,3 +187,205 @@ func TestJobSubcollector(t *testing.T) {
 		})
 	}
 }
+
+func TestJobSubcollectorImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		jobs       []*brigade.Job
+		expMetrics []metricResult
+	}{
+		{
+			name: "Job images should be aggregated by registry, repository and tag.",
+			jobs: []*brigade.Job{
+				&brigade.Job{ID: "id1", Image: "alpine"},
+				&brigade.Job{ID: "id2", Image: "alpine:latest"},
+				&brigade.Job{ID: "id3", Image: "docker.io/library/alpine"},
+				&brigade.Job{ID: "id4", Image: "golang:1.11"},
+				&brigade.Job{ID: "id5", Image: "quay.io/slok/brigade-exporter:v0.3.0"},
+				&brigade.Job{ID: "id6", Image: "localhost:5000/team/app"},
+				&brigade.Job{ID: "id7", Image: "registry.example.com:5000/team/app@sha256:0123456789abcdef"},
+				&brigade.Job{ID: "id8", Image: "deis/brigade-worker:v0.19.0"},
+				&brigade.Job{ID: "id9", Image: ""},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "deis/brigade-worker", "tag": "v0.19.0"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/alpine", "tag": "latest"},
+					value:      3,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/golang", "tag": "1.11"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "localhost:5000", "repository": "team/app", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "quay.io", "repository": "slok/brigade-exporter", "tag": "v0.3.0"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       jobImagesDesc,
+					labels:     labelMap{"registry": "registry.example.com:5000", "repository": "team/app", "tag": "sha256:0123456789abcdef"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetJobs").Once().Return(test.jobs, nil)
+
+			clr := collector.NewJob(collector.JobConfig{}, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the image metrics.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == jobImagesDesc {
+					got = append(got, mr)
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestJobSubcollectorStatusStateSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        collector.JobConfig
+		jobs       []*brigade.Job
+		expMetrics []metricResult
+	}{
+		{
+			name: "With state set disabled the status metric should be sent as it is.",
+			jobs: []*brigade.Job{
+				&brigade.Job{ID: "id1", Status: "Running"},
+				&brigade.Job{ID: "id2", Status: "Exploded"},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Exploded"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+		{
+			name: "With state set enabled every known status should be sent and the unknown statuses normalized.",
+			cfg:  collector.JobConfig{StatusStateSet: true},
+			jobs: []*brigade.Job{
+				&brigade.Job{ID: "id1", Status: "Running"},
+				&brigade.Job{ID: "id2", Status: "Exploded"},
+			},
+			expMetrics: []metricResult{
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Pending"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Succeeded"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Failed"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Unknown"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Pending"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Running"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Succeeded"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Failed"}, value: 0, metricType: dto.MetricType_GAUGE},
+				metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Unknown"}, value: 1, metricType: dto.MetricType_GAUGE},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetJobs").Once().Return(test.jobs, nil)
+
+			clr := collector.NewJob(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the status metrics.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == jobStatusDesc {
+					got = append(got, mr)
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestJobSubcollectorSeriesLimit(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	// Mocks, the pending job has not started so it will use the creation time.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetJobs").Once().Return([]*brigade.Job{
+		&brigade.Job{ID: "id1", Status: "Succeeded", Start: now.Add(-1 * time.Hour)},
+		&brigade.Job{ID: "id2", Status: "Pending", Creation: now.Add(-3 * time.Hour)},
+		&brigade.Job{ID: "id3", Status: "Failed", Start: now.Add(-2 * time.Hour)},
+		&brigade.Job{ID: "id4", Status: "Running", Start: now.Add(-2 * time.Hour)},
+	}, nil)
+
+	clr := collector.NewJob(collector.JobConfig{MaxSeries: 15}, mbsvc, log.Dummy)
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		clr.Collect(context.TODO(), ch)
+		close(ch)
+	}()
+
+	// Get only the status metrics.
+	var got []metricResult
+	for m := range ch {
+		mr := readMetric(m)
+		if mr.desc == jobStatusDesc {
+			got = append(got, mr)
+		}
+	}
+
+	exp := []metricResult{
+		metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id4", "status": "Running"}, value: 1, metricType: dto.MetricType_GAUGE},
+		metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id2", "status": "Pending"}, value: 1, metricType: dto.MetricType_GAUGE},
+		metricResult{desc: jobStatusDesc, labels: labelMap{"id": "id1", "status": "Succeeded"}, value: 1, metricType: dto.MetricType_GAUGE},
+	}
+	assert.Equal(exp, got)
+}
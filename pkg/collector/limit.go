@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+// seriesLimiter is implemented by the subcollectors that can limit the number
+// of series they send.
+type seriesLimiter interface {
+	// maxSeries returns the max number of series, 0 means no limit.
+	maxSeries() int
+	// seriesDropped returns the total number of dropped series and if
+	// the limit was hit on the last collection.
+	seriesDropped() (total float64, limitHit bool)
+}
+
+// seriesLimit limits the number of series of a subcollector and tracks
+// the dropped series.
+// Satisfies seriesLimiter interface.
+type seriesLimit struct {
+	max int
+
+	mu       sync.Mutex
+	dropped  float64
+	limitHit bool
+}
+
+func (s *seriesLimit) maxSeries() int { return s.max }
+
+func (s *seriesLimit) seriesDropped() (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped, s.limitHit
+}
+
+// fit returns how many objects of the total fit in the limit knowing the number
+// of series that every object has, it will track the dropped series.
+func (s *seriesLimit) fit(objects, seriesPerObject int) int {
+	keep := objects
+	if s.max > 0 && objects*seriesPerObject > s.max {
+		keep = s.max / seriesPerObject
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limitHit = keep < objects
+	s.dropped += float64((objects - keep) * seriesPerObject)
+
+	return keep
+}
+
+// limitMetrics returns the metrics that fit in the series limit, the last ones are dropped.
+func (s *seriesLimit) limitMetrics(metrics []prometheus.Metric) []prometheus.Metric {
+	return metrics[:s.fit(len(metrics), 1)]
+}
+
+// isTerminal returns if the status is a final status.
+func isTerminal(status string) bool {
+	return status == brigade.StatusSucceeded || status == brigade.StatusFailed
+}
+
+// byPriority sorts the objects by priority so when limiting the series the most
+// important are kept first, the non terminal ones and then the most recent ones.
+func byPriority(status func(i int) string, start func(i int) time.Time) func(i, j int) bool {
+	return func(i, j int) bool {
+		ti, tj := isTerminal(status(i)), isTerminal(status(j))
+		if ti != tj {
+			return !ti
+		}
+		return start(i).After(start(j))
+	}
+}
+
+// limitBuilds returns the builds that fit in the series limit.
+func (s *seriesLimit) limitBuilds(blds []*brigade.Build, seriesPerBuild int) []*brigade.Build {
+	keep := s.fit(len(blds), seriesPerBuild)
+	if keep == len(blds) {
+		return blds
+	}
+
+	sorted := make([]*brigade.Build, len(blds))
+	copy(sorted, blds)
+	sort.SliceStable(sorted, byPriority(
+		func(i int) string { return sorted[i].Status },
+		func(i int) time.Time { return sorted[i].Start }))
+
+	return sorted[:keep]
+}
+
+// limitJobs returns the jobs that fit in the series limit.
+func (s *seriesLimit) limitJobs(jobs []*brigade.Job, seriesPerJob int) []*brigade.Job {
+	keep := s.fit(len(jobs), seriesPerJob)
+	if keep == len(jobs) {
+		return jobs
+	}
+
+	sorted := make([]*brigade.Job, len(jobs))
+	copy(sorted, jobs)
+	sort.SliceStable(sorted, byPriority(
+		func(i int) string { return sorted[i].Status },
+		func(i int) time.Time {
+			if sorted[i].Start.IsZero() {
+				return sorted[i].Creation
+			}
+			return sorted[i].Start
+		}))
+
+	return sorted[:keep]
+}
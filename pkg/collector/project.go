@@ -2,6 +2,9 @@ package collector
 
 import (
 	"context"
+	"math"
+	"sort"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -11,34 +14,153 @@ import (
 
 const (
 	projectSubSystem = "project"
+	workerSubSystem  = "worker"
+
+	// noBuildStatus is the last build status of the projects without builds.
+	noBuildStatus = "None"
+
+	// defaultWorkerImageDef is the worker image of the projects without a custom worker.
+	defaultWorkerImageDef = "brigadecore/brigade-worker:latest"
 )
 
+// ProjectConfig is the configuration of the project subcollector.
+type ProjectConfig struct {
+	// BlessedWorkerImages are the worker images that the projects should use,
+	// if empty the worker images will not be checked. The projects using a newer
+	// version of a blessed image repository are not outdated.
+	BlessedWorkerImages []string
+	// DefaultWorkerImage is the worker image used by the projects without a
+	// custom worker, by default brigadecore/brigade-worker:latest.
+	DefaultWorkerImage string
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
 // project is the Brigade project subcollector. this colletor will collect
 // the metrics regarding brigade projects.
 // Satisfies internfal collector interface.
 type project struct {
-	brigadeSVC brigade.Interface
-	logger     log.Logger
+	cfg ProjectConfig
+	// blessedTags are the blessed tags by image registry and repository.
+	blessedTags map[string][]string
+	brigadeSVC  brigade.Interface
+	logger      log.Logger
 
 	// Metrics.
-	projectInfoDesc *prometheus.Desc
+	projectInfoDesc                 *metricDesc
+	projectAllowPrivilegedDesc      *metricDesc
+	projectAllowHostMountsDesc      *metricDesc
+	projectInitGitSubmodulesDesc    *metricDesc
+	projectDefaultScriptDesc        *metricDesc
+	projectSecretsDesc              *metricDesc
+	projectBuildStorageSizeDesc     *metricDesc
+	projectStorageInfoDesc          *metricDesc
+	projectLastBuildStatusDesc      *metricDesc
+	projectLastBuildTimeDesc        *metricDesc
+	projectLastSuccessTimeDesc      *metricDesc
+	projectTimeSinceLastSuccessDesc *metricDesc
+	projectWorkerImagesDesc         *metricDesc
+	projectWorkerOutdatedDesc       *metricDesc
+	workerImageProjectsDesc         *metricDesc
 }
 
 // NewProject returns a new project subcollector.
-func NewProject(brigadeSVC brigade.Interface, logger log.Logger) subcollector {
+func NewProject(cfg ProjectConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
+	if cfg.DefaultWorkerImage == "" {
+		cfg.DefaultWorkerImage = defaultWorkerImageDef
+	}
+
+	blessedTags := map[string][]string{}
+	for _, image := range cfg.BlessedWorkerImages {
+		ref := parseImage(image)
+		blessedTags[ref.name()] = append(blessedTags[ref.name()], ref.tag)
+	}
+
 	return &project{
-		brigadeSVC: brigadeSVC,
-		logger:     logger,
+		cfg:         cfg,
+		blessedTags: blessedTags,
+		brigadeSVC:  brigadeSVC,
+		logger:      logger,
 
-		projectInfoDesc: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, projectSubSystem, "info"),
+		projectInfoDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "info",
 			"Brigade project information.",
-			[]string{"id", "name", "repository", "namespace", "worker"}, nil,
+			[]string{"id", "name", "repository", "namespace", "worker"},
+		),
+		projectAllowPrivilegedDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_allow_privileged_jobs",
+			"Whether the Brigade project allows privileged jobs.",
+			[]string{"id"},
+		),
+		projectAllowHostMountsDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_allow_host_mounts",
+			"Whether the Brigade project allows jobs to mount the host.",
+			[]string{"id"},
+		),
+		projectInitGitSubmodulesDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_init_git_submodules",
+			"Whether the Brigade project initializes the git submodules.",
+			[]string{"id"},
+		),
+		projectDefaultScriptDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_default_script",
+			"Whether the Brigade project has a default script.",
+			[]string{"id"},
+		),
+		projectSecretsDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_secrets",
+			"Number of secrets of the Brigade project.",
+			[]string{"id"},
+		),
+		projectBuildStorageSizeDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_build_storage_size_bytes",
+			"Brigade project build storage size in bytes.",
+			[]string{"id"},
+		),
+		projectStorageInfoDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "config_storage_info",
+			"Brigade project storage information.",
+			[]string{"id", "build_storage_class", "cache_storage_class"},
+		),
+		projectLastBuildStatusDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "last_build_status",
+			"Brigade project last build status.",
+			[]string{"id", "status"},
+		),
+		projectLastBuildTimeDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "last_build_time_seconds",
+			"Brigade project last build start time (or creation if not started) in unix timestamp.",
+			[]string{"id"},
+		),
+		projectLastSuccessTimeDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "last_successful_build_time_seconds",
+			"Brigade project last successful build end time in unix timestamp.",
+			[]string{"id"},
+		),
+		projectTimeSinceLastSuccessDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "time_since_last_successful_build_seconds",
+			"Time since the Brigade project last successful build ended in seconds.",
+			[]string{"id"},
+		),
+		projectWorkerImagesDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "worker_images",
+			"Number of Brigade projects using the worker image, the projects without custom worker use the default worker image.",
+			[]string{"registry", "repository", "tag"},
+		),
+		projectWorkerOutdatedDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "worker_image_outdated",
+			"Whether the Brigade project worker image is not a blessed worker image or is older than the blessed version.",
+			[]string{"project_id"},
+		),
+		workerImageProjectsDesc: cfg.Metrics.newDesc(
+			workerSubSystem, "image_projects",
+			"Number of Brigade projects by worker image, the projects without custom worker use the default worker image.",
+			[]string{"image"},
 		),
 	}
 }
 
-// Collect satisfies subcollector.
+// Collect satisfies Subcollector.
 func (p *project) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
 	// Collect project info.
 	prs, err := p.brigadeSVC.GetProjects()
@@ -46,9 +168,37 @@ func (p *project) Collect(ctx context.Context, ch chan<- prometheus.Metric) erro
 		return err
 	}
 
-	for _, pr := range prs {
-		err := sendMetric(ctx, ch, prometheus.MustNewConstMetric(
-			p.projectInfoDesc,
+	blds, err := p.brigadeSVC.GetBuilds()
+	if err != nil {
+		return err
+	}
+
+	// Get the last builds of the projects, the pending builds are the last ones
+	// even if they have not started.
+	lastBlds := map[string]*brigade.Build{}
+	lastSuccessBlds := map[string]*brigade.Build{}
+	for _, bld := range blds {
+		if last, ok := lastBlds[bld.ProjectID]; !ok || bld.Created().After(last.Created()) {
+			lastBlds[bld.ProjectID] = bld
+		}
+
+		if bld.Status != brigade.StatusSucceeded {
+			continue
+		}
+		if last, ok := lastSuccessBlds[bld.ProjectID]; !ok || bld.End.After(last.End) {
+			lastSuccessBlds[bld.ProjectID] = bld
+		}
+	}
+
+	now := time.Now()
+	images := make([]string, len(prs))
+	for i, pr := range prs {
+		images[i] = pr.Worker
+		if images[i] == "" {
+			images[i] = p.cfg.DefaultWorkerImage
+		}
+
+		err := sendMetric(ctx, ch, p.projectInfoDesc.newConstMetric(
 			prometheus.GaugeValue,
 			1,
 			pr.ID, pr.Name, pr.Repository, pr.Namespace, pr.Worker))
@@ -56,7 +206,104 @@ func (p *project) Collect(ctx context.Context, ch chan<- prometheus.Metric) erro
 		if err != nil {
 			return err
 		}
+
+		// Configuration metrics.
+		cfg := pr.Config
+		metrics := []prometheus.Metric{
+			p.projectAllowPrivilegedDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(cfg.AllowPrivilegedJobs), pr.ID),
+			p.projectAllowHostMountsDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(cfg.AllowHostMounts), pr.ID),
+			p.projectInitGitSubmodulesDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(cfg.InitGitSubmodules), pr.ID),
+			p.projectDefaultScriptDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(cfg.DefaultScript), pr.ID),
+			p.projectSecretsDesc.newConstMetric(prometheus.GaugeValue, float64(cfg.Secrets), pr.ID),
+			p.projectBuildStorageSizeDesc.newConstMetric(prometheus.GaugeValue, float64(cfg.BuildStorageSize), pr.ID),
+			p.projectStorageInfoDesc.newConstMetric(prometheus.GaugeValue, 1, pr.ID, cfg.BuildStorageClass, cfg.CacheStorageClass),
+		}
+
+		// Last build metrics, the projects without builds will have them also.
+		lastStatus := noBuildStatus
+		var lastTime time.Time
+		if bld, ok := lastBlds[pr.ID]; ok {
+			lastStatus = bld.Status
+			lastTime = bld.Start
+			if lastTime.IsZero() {
+				lastTime = bld.Creation
+			}
+		}
+
+		// If never succeeded then the time since the last success is infinite.
+		var lastSuccessTime time.Time
+		timeSinceLastSuccess := math.Inf(1)
+		if bld, ok := lastSuccessBlds[pr.ID]; ok && !bld.End.IsZero() {
+			lastSuccessTime = bld.End
+			timeSinceLastSuccess = now.Sub(bld.End).Seconds()
+		}
+
+		metrics = append(metrics,
+			p.projectLastBuildStatusDesc.newConstMetric(prometheus.GaugeValue, 1, pr.ID, lastStatus),
+			p.projectLastBuildTimeDesc.newConstMetric(prometheus.GaugeValue, getUnix(lastTime), pr.ID),
+			p.projectLastSuccessTimeDesc.newConstMetric(prometheus.GaugeValue, getUnix(lastSuccessTime), pr.ID),
+			p.projectTimeSinceLastSuccessDesc.newConstMetric(prometheus.GaugeValue, timeSinceLastSuccess, pr.ID),
+		)
+
+		// Worker drift metrics, only if we know what images are blessed.
+		if len(p.blessedTags) > 0 {
+			outdated := p.workerOutdated(parseImage(images[i]))
+			metrics = append(metrics, p.projectWorkerOutdatedDesc.newConstMetric(prometheus.GaugeValue, boolToFloat(outdated), pr.ID))
+		}
+		for _, m := range metrics {
+			if err := sendMetric(ctx, ch, m); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Worker images metrics.
+	refs, counts := countImages(images)
+	for _, ref := range refs {
+		err := sendMetric(ctx, ch, p.projectWorkerImagesDesc.newConstMetric(
+			prometheus.GaugeValue,
+			counts[ref],
+			ref.registry, ref.repository, ref.tag))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Worker image distribution metrics.
+	imageProjects := map[string]float64{}
+	for _, image := range images {
+		imageProjects[image]++
+	}
+	sortedImages := make([]string, 0, len(imageProjects))
+	for image := range imageProjects {
+		sortedImages = append(sortedImages, image)
+	}
+	sort.Strings(sortedImages)
+	for _, image := range sortedImages {
+		err := sendMetric(ctx, ch, p.workerImageProjectsDesc.newConstMetric(
+			prometheus.GaugeValue,
+			imageProjects[image],
+			image))
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// workerOutdated returns if the worker image is not a blessed image, the images of a
+// blessed repository with a version tag are outdated only if they are older than all
+// the blessed versions.
+func (p *project) workerOutdated(ref imageRef) bool {
+	for _, tag := range p.blessedTags[ref.name()] {
+		if ref.tag == tag {
+			return false
+		}
+		if cmp, ok := compareVersions(ref.tag, tag); ok && cmp >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,7 +2,9 @@ package collector_test
 
 import (
 	"context"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -15,13 +17,35 @@ import (
 )
 
 const (
-	projectInfoDesc = `Desc{fqName: "brigade_project_info", help: "Brigade project information.", constLabels: {}, variableLabels: [id name repository namespace worker]}`
+	projectInfoDesc                 = `Desc{fqName: "brigade_project_info", help: "Brigade project information.", constLabels: {}, variableLabels: [id name repository namespace worker]}`
+	projectAllowPrivilegedDesc      = `Desc{fqName: "brigade_project_config_allow_privileged_jobs", help: "Whether the Brigade project allows privileged jobs.", constLabels: {}, variableLabels: [id]}`
+	projectAllowHostMountsDesc      = `Desc{fqName: "brigade_project_config_allow_host_mounts", help: "Whether the Brigade project allows jobs to mount the host.", constLabels: {}, variableLabels: [id]}`
+	projectInitGitSubmodulesDesc    = `Desc{fqName: "brigade_project_config_init_git_submodules", help: "Whether the Brigade project initializes the git submodules.", constLabels: {}, variableLabels: [id]}`
+	projectDefaultScriptDesc        = `Desc{fqName: "brigade_project_config_default_script", help: "Whether the Brigade project has a default script.", constLabels: {}, variableLabels: [id]}`
+	projectSecretsDesc              = `Desc{fqName: "brigade_project_config_secrets", help: "Number of secrets of the Brigade project.", constLabels: {}, variableLabels: [id]}`
+	projectBuildStorageSizeDesc     = `Desc{fqName: "brigade_project_config_build_storage_size_bytes", help: "Brigade project build storage size in bytes.", constLabels: {}, variableLabels: [id]}`
+	projectStorageInfoDesc          = `Desc{fqName: "brigade_project_config_storage_info", help: "Brigade project storage information.", constLabels: {}, variableLabels: [id build_storage_class cache_storage_class]}`
+	projectLastBuildStatusDesc      = `Desc{fqName: "brigade_project_last_build_status", help: "Brigade project last build status.", constLabels: {}, variableLabels: [id status]}`
+	projectLastBuildTimeDesc        = `Desc{fqName: "brigade_project_last_build_time_seconds", help: "Brigade project last build start time (or creation if not started) in unix timestamp.", constLabels: {}, variableLabels: [id]}`
+	projectLastSuccessTimeDesc      = `Desc{fqName: "brigade_project_last_successful_build_time_seconds", help: "Brigade project last successful build end time in unix timestamp.", constLabels: {}, variableLabels: [id]}`
+	projectTimeSinceLastSuccessDesc = `Desc{fqName: "brigade_project_time_since_last_successful_build_seconds", help: "Time since the Brigade project last successful build ended in seconds.", constLabels: {}, variableLabels: [id]}`
+	projectWorkerImagesDesc         = `Desc{fqName: "brigade_project_worker_images", help: "Number of Brigade projects using the worker image, the projects without custom worker use the default worker image.", constLabels: {}, variableLabels: [registry repository tag]}`
+	projectWorkerOutdatedDesc       = `Desc{fqName: "brigade_project_worker_image_outdated", help: "Whether the Brigade project worker image is not a blessed worker image or is older than the blessed version.", constLabels: {}, variableLabels: [project_id]}`
+	workerImageProjectsDesc         = `Desc{fqName: "brigade_worker_image_projects", help: "Number of Brigade projects by worker image, the projects without custom worker use the default worker image.", constLabels: {}, variableLabels: [image]}`
 )
 
 func TestProjectSubcollector(t *testing.T) {
+	// Test times.
+	now := time.Now()
+	t1 := now.Add(-3 * time.Hour)
+	t2 := now.Add(-2 * time.Hour)
+	t3 := now.Add(-1 * time.Hour)
+
 	tests := []struct {
 		name       string
+		cfg        collector.ProjectConfig
 		projects   []*brigade.Project
+		builds     []*brigade.Build
 		expMetrics []metricResult
 	}{
 		{
@@ -38,18 +62,444 @@ func TestProjectSubcollector(t *testing.T) {
 					value:      1,
 					metricType: dto.MetricType_GAUGE,
 				},
+				metricResult{
+					desc:       projectAllowPrivilegedDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowHostMountsDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectInitGitSubmodulesDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectDefaultScriptDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectSecretsDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectBuildStorageSizeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectStorageInfoDesc,
+					labels:     labelMap{"id": "id1", "build_storage_class": "", "cache_storage_class": ""},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id1", "status": "None"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastSuccessTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectTimeSinceLastSuccessDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      math.Inf(1),
+					metricType: dto.MetricType_GAUGE,
+				},
 				metricResult{
 					desc:       projectInfoDesc,
 					labels:     labelMap{"id": "id2", "name": "Name2", "repository": "repo2", "namespace": "ns2", "worker": "worker2"},
 					value:      1,
 					metricType: dto.MetricType_GAUGE,
 				},
+				metricResult{
+					desc:       projectAllowPrivilegedDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowHostMountsDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectInitGitSubmodulesDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectDefaultScriptDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectSecretsDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectBuildStorageSizeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectStorageInfoDesc,
+					labels:     labelMap{"id": "id2", "build_storage_class": "", "cache_storage_class": ""},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id2", "status": "None"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastSuccessTimeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectTimeSinceLastSuccessDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      math.Inf(1),
+					metricType: dto.MetricType_GAUGE,
+				},
 				metricResult{
 					desc:       projectInfoDesc,
 					labels:     labelMap{"id": "id3", "name": "Name3", "repository": "repo3", "namespace": "ns3", "worker": "worker3"},
 					value:      1,
 					metricType: dto.MetricType_GAUGE,
 				},
+				metricResult{
+					desc:       projectAllowPrivilegedDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowHostMountsDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectInitGitSubmodulesDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectDefaultScriptDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectSecretsDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectBuildStorageSizeDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectStorageInfoDesc,
+					labels:     labelMap{"id": "id3", "build_storage_class": "", "cache_storage_class": ""},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id3", "status": "None"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastSuccessTimeDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectTimeSinceLastSuccessDesc,
+					labels:     labelMap{"id": "id3"},
+					value:      math.Inf(1),
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/worker1", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/worker2", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/worker3", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "worker1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "worker2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "worker3"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "Projects should have their configuration and the summary of their last builds.",
+			projects: []*brigade.Project{
+				&brigade.Project{ID: "id1", Name: "Name1", Repository: "repo1", Namespace: "ns1", Worker: "worker1", Config: brigade.ProjectConfig{
+					AllowPrivilegedJobs: true,
+					InitGitSubmodules:   true,
+					DefaultScript:       true,
+					Secrets:             3,
+					BuildStorageSize:    52428800,
+					BuildStorageClass:   "ssd",
+					CacheStorageClass:   "standard",
+				}},
+				&brigade.Project{ID: "id2", Name: "Name2", Repository: "repo2", Namespace: "ns2", Worker: "worker2"},
+			},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "bld1", ProjectID: "id1", Status: "Succeeded", Start: t1, End: t1.Add(10 * time.Minute)},
+				&brigade.Build{ID: "bld2", ProjectID: "id1", Status: "Running", Start: t3},
+				&brigade.Build{ID: "bld3", ProjectID: "id1", Status: "Failed", Start: t2, End: t2.Add(5 * time.Minute)},
+				&brigade.Build{ID: "bld4", ProjectID: "id2", Status: "Failed", Start: t1, End: t1.Add(5 * time.Minute)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       projectInfoDesc,
+					labels:     labelMap{"id": "id1", "name": "Name1", "repository": "repo1", "namespace": "ns1", "worker": "worker1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowPrivilegedDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowHostMountsDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectInitGitSubmodulesDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectDefaultScriptDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectSecretsDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      3,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectBuildStorageSizeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      52428800,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectStorageInfoDesc,
+					labels:     labelMap{"id": "id1", "build_storage_class": "ssd", "cache_storage_class": "standard"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id1", "status": "Running"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      float64(t3.Unix()),
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastSuccessTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      float64(t1.Add(10 * time.Minute).Unix()),
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectTimeSinceLastSuccessDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      now.Sub(t1.Add(10 * time.Minute)).Seconds(),
+					metricType: dto.MetricType_GAUGE,
+				},
+
+				metricResult{
+					desc:       projectInfoDesc,
+					labels:     labelMap{"id": "id2", "name": "Name2", "repository": "repo2", "namespace": "ns2", "worker": "worker2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowPrivilegedDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectAllowHostMountsDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectInitGitSubmodulesDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectDefaultScriptDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectSecretsDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectBuildStorageSizeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectStorageInfoDesc,
+					labels:     labelMap{"id": "id2", "build_storage_class": "", "cache_storage_class": ""},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id2", "status": "Failed"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      float64(t1.Unix()),
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastSuccessTimeDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectTimeSinceLastSuccessDesc,
+					labels:     labelMap{"id": "id2"},
+					value:      math.Inf(1),
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/worker1", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "library/worker2", "tag": "latest"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "worker1"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "worker2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
 			},
 		},
 	}
@@ -61,8 +511,9 @@ func TestProjectSubcollector(t *testing.T) {
 			// Mocks.
 			mbsvc := &mbrigade.Interface{}
 			mbsvc.On("GetProjects").Once().Return(test.projects, nil)
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
 
-			clr := collector.NewProject(mbsvc, log.Dummy)
+			clr := collector.NewProject(test.cfg, mbsvc, log.Dummy)
 
 			ch := make(chan prometheus.Metric)
 
@@ -77,6 +528,264 @@ func TestProjectSubcollector(t *testing.T) {
 				got = append(got, readMetric(m))
 			}
 
+			// The time since the last success depends on the collection time.
+			if assert.Len(got, len(test.expMetrics)) {
+				for i, m := range got {
+					if m.desc == projectTimeSinceLastSuccessDesc && !math.IsInf(m.value, 1) {
+						assert.InDelta(test.expMetrics[i].value, m.value, 5)
+						got[i].value = test.expMetrics[i].value
+					}
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestProjectSubcollectorWorkerImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        collector.ProjectConfig
+		projects   []*brigade.Project
+		expMetrics []metricResult
+	}{
+		{
+			name: "Without blessed worker images the projects should not be checked and the projects without worker should use the default image.",
+			cfg: collector.ProjectConfig{
+				DefaultWorkerImage: "brigadecore/brigade-worker:v1.0.0",
+			},
+			projects: []*brigade.Project{
+				&brigade.Project{ID: "id1", Worker: "brigadecore/brigade-worker:v1.0.0"},
+				&brigade.Project{ID: "id2", Worker: "docker.io/brigadecore/brigade-worker:v1.0.0"},
+				&brigade.Project{ID: "id3"},
+				&brigade.Project{ID: "id4", Worker: "quay.io/team/worker:v2"},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "docker.io", "repository": "brigadecore/brigade-worker", "tag": "v1.0.0"},
+					value:      3,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerImagesDesc,
+					labels:     labelMap{"registry": "quay.io", "repository": "team/worker", "tag": "v2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "brigadecore/brigade-worker:v1.0.0"},
+					value:      2,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "docker.io/brigadecore/brigade-worker:v1.0.0"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       workerImageProjectsDesc,
+					labels:     labelMap{"image": "quay.io/team/worker:v2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "With blessed worker images the projects using other images or older versions should be outdated.",
+			cfg: collector.ProjectConfig{
+				BlessedWorkerImages: []string{"brigadecore/brigade-worker:v1.0.0", "quay.io/team/worker:v2"},
+			},
+			projects: []*brigade.Project{
+				&brigade.Project{ID: "id1", Worker: "docker.io/brigadecore/brigade-worker:v1.0.0"},
+				&brigade.Project{ID: "id2", Worker: "brigadecore/brigade-worker:v0.19.0"},
+				&brigade.Project{ID: "id3", Worker: "brigadecore/brigade-worker:v1.1.0"},
+				&brigade.Project{ID: "id4", Worker: "brigadecore/brigade-worker:v1.1.0-rc.1"},
+				&brigade.Project{ID: "id5", Worker: "brigadecore/brigade-worker:v1.0.0-rc.1"},
+				&brigade.Project{ID: "id6", Worker: "quay.io/team/worker:v2.0.1"},
+				&brigade.Project{ID: "id7", Worker: "team/worker:v2"},
+				&brigade.Project{ID: "id8", Worker: "quay.io/team/worker:latest"},
+				&brigade.Project{ID: "id9"},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id1"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id2"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id3"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id4"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id5"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id6"},
+					value:      0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id7"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id8"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectWorkerOutdatedDesc,
+					labels:     labelMap{"project_id": "id9"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetProjects").Once().Return(test.projects, nil)
+			mbsvc.On("GetBuilds").Once().Return([]*brigade.Build{}, nil)
+
+			clr := collector.NewProject(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the worker image metrics of the test.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == projectWorkerOutdatedDesc || (len(test.cfg.BlessedWorkerImages) == 0 && (mr.desc == projectWorkerImagesDesc || mr.desc == workerImageProjectsDesc)) {
+					got = append(got, mr)
+				}
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestProjectSubcollectorLastBuild(t *testing.T) {
+	now := time.Now()
+	t1 := now.Add(-3 * time.Hour)
+	t2 := now.Add(-2 * time.Hour)
+	t3 := now.Add(-1 * time.Hour)
+
+	tests := []struct {
+		name       string
+		builds     []*brigade.Build
+		expMetrics []metricResult
+	}{
+		{
+			name: "A pending build newer than the finished builds should be the last build.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "bld1", ProjectID: "id1", Status: "Succeeded", Creation: t1, Start: t1, End: t1.Add(10 * time.Minute)},
+				&brigade.Build{ID: "bld2", ProjectID: "id1", Status: "Pending", Creation: t3},
+				&brigade.Build{ID: "bld3", ProjectID: "id1", Status: "Failed", Creation: t2, Start: t2, End: t2.Add(5 * time.Minute)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id1", "status": "Pending"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      float64(t3.Unix()),
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "The builds without creation should be ordered by their start.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "bld1", ProjectID: "id1", Status: "Failed", Start: t2, End: t2.Add(5 * time.Minute)},
+				&brigade.Build{ID: "bld2", ProjectID: "id1", Status: "Succeeded", Start: t1, End: t1.Add(10 * time.Minute)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       projectLastBuildStatusDesc,
+					labels:     labelMap{"id": "id1", "status": "Failed"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       projectLastBuildTimeDesc,
+					labels:     labelMap{"id": "id1"},
+					value:      float64(t2.Unix()),
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetProjects").Once().Return([]*brigade.Project{&brigade.Project{ID: "id1"}}, nil)
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+
+			clr := collector.NewProject(collector.ProjectConfig{}, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get only the last build metrics.
+			var got []metricResult
+			for m := range ch {
+				mr := readMetric(m)
+				if mr.desc == projectLastBuildStatusDesc || mr.desc == projectLastBuildTimeDesc {
+					got = append(got, mr)
+				}
+			}
+
 			// Check metrics are ok.
 			assert.Equal(test.expMetrics, got)
 		})
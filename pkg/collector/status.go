@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+// statuses are all the known Brigade build and job statuses.
+var statuses = []string{
+	brigade.StatusPending,
+	brigade.StatusRunning,
+	brigade.StatusSucceeded,
+	brigade.StatusFailed,
+	brigade.StatusUnknown,
+}
+
+// normalizeStatus returns the status if is a known status, otherwise
+// it returns the unknown status.
+func normalizeStatus(status string) string {
+	for _, s := range statuses {
+		if s == status {
+			return s
+		}
+	}
+
+	return brigade.StatusUnknown
+}
+
+// statusSeries returns the number of status series sent for every object.
+func statusSeries(stateSet bool) int {
+	if stateSet {
+		return len(statuses)
+	}
+
+	return 1
+}
+
+// sendStatusMetric will send the status metric of an object, if state set is enabled it
+// will send a metric for every known status (OpenMetrics state set style) with 1 on the
+// status of the object and 0 on the rest, otherwise it will send a single metric with
+// the status as it is.
+func sendStatusMetric(ctx context.Context, ch chan<- prometheus.Metric, desc *metricDesc, stateSet bool, id, status string) error {
+	if !stateSet {
+		return sendMetric(ctx, ch, desc.newConstMetric(prometheus.GaugeValue, 1, id, status))
+	}
+
+	status = normalizeStatus(status)
+	for _, s := range statuses {
+		err := sendMetric(ctx, ch, desc.newConstMetric(prometheus.GaugeValue, boolToFloat(s == status), id, s))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+var (
+	// Defaults.
+	buildSuccessRatioWindowsDef = []time.Duration{1 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+)
+
+// BuildSuccessRatioConfig is the configuration of the build success ratio subcollector.
+type BuildSuccessRatioConfig struct {
+	// Windows are the sliding windows used to calculate the success ratio.
+	Windows []time.Duration
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig
+}
+
+// defaults sets the required defaults.
+func (c *BuildSuccessRatioConfig) defaults() {
+	if len(c.Windows) == 0 {
+		c.Windows = buildSuccessRatioWindowsDef
+	}
+}
+
+// finishedBuild is a build that has been finished.
+type finishedBuild struct {
+	projectID string
+	succeeded bool
+	end       time.Time
+}
+
+// buildSuccessRatio is the Brigade build success ratio subcollector. this collector
+// will keep a record of the finished builds in a time window so it can calculate
+// the ratio of successful builds of each project.
+// Satisfies internal collector interface.
+type buildSuccessRatio struct {
+	cfg        BuildSuccessRatioConfig
+	maxWindow  time.Duration
+	brigadeSVC brigade.Interface
+	logger     log.Logger
+
+	// State.
+	mu       sync.Mutex
+	finished map[string]finishedBuild
+	// stamped are the ends given to the finished builds without end when they were
+	// tracked, kept while Brigade has the builds so they are not tracked again with
+	// a new end once they are out of the windows.
+	stamped map[string]time.Time
+
+	// Metrics.
+	buildSuccessRatioDesc *metricDesc
+}
+
+// NewBuildSuccessRatio returns a new build success ratio subcollector.
+func NewBuildSuccessRatio(cfg BuildSuccessRatioConfig, brigadeSVC brigade.Interface, logger log.Logger) Subcollector {
+	cfg.defaults()
+
+	var maxWindow time.Duration
+	for _, w := range cfg.Windows {
+		if w > maxWindow {
+			maxWindow = w
+		}
+	}
+
+	return &buildSuccessRatio{
+		cfg:        cfg,
+		maxWindow:  maxWindow,
+		brigadeSVC: brigadeSVC,
+		logger:     logger,
+
+		finished: map[string]finishedBuild{},
+		stamped:  map[string]time.Time{},
+
+		buildSuccessRatioDesc: cfg.Metrics.newDesc(
+			projectSubSystem, "build_success_ratio",
+			"Ratio of the finished Brigade builds of the project that succeeded in the window.",
+			[]string{"project_id", "window"},
+		),
+	}
+}
+
+// inherit satisfies stateful, the finished builds are kept.
+func (b *buildSuccessRatio) inherit(prev Subcollector) {
+	p, ok := prev.(*buildSuccessRatio)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, bld := range p.finished {
+		b.finished[id] = bld
+	}
+	for id, end := range p.stamped {
+		b.stamped[id] = end
+	}
+}
+
+// Collect satisfies Subcollector.
+func (b *buildSuccessRatio) Collect(ctx context.Context, ch chan<- prometheus.Metric) error {
+	blds, err := b.brigadeSVC.GetBuilds()
+	if err != nil {
+		return err
+	}
+
+	ratios := b.track(blds, time.Now())
+
+	// Sort so the metrics are always sent in the same order.
+	prjs := make([]string, 0, len(ratios))
+	for prj := range ratios {
+		prjs = append(prjs, prj)
+	}
+	sort.Strings(prjs)
+
+	for _, prj := range prjs {
+		for i, w := range b.cfg.Windows {
+			ratio := ratios[prj][i]
+			// Without finished builds in the window there is no ratio.
+			if ratio < 0 {
+				continue
+			}
+
+			err := sendMetric(ctx, ch, b.buildSuccessRatioDesc.newConstMetric(
+				prometheus.GaugeValue,
+				ratio,
+				prj, formatWindow(w)))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// track will update the state with the finished builds and return the success ratio
+// of each project for every window (in the same order as the configured windows), if
+// a project doesn't have finished builds in a window the ratio will be negative.
+func (b *buildSuccessRatio) track(blds []*brigade.Build, now time.Time) map[string][]float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	present := make(map[string]bool, len(blds))
+	for _, bld := range blds {
+		present[bld.ID] = true
+		if bld.Status != brigade.StatusSucceeded && bld.Status != brigade.StatusFailed {
+			continue
+		}
+		if _, ok := b.finished[bld.ID]; ok {
+			continue
+		}
+
+		// The builds without end ended when they were tracked the first time.
+		end := bld.End
+		if end.IsZero() {
+			stamped, ok := b.stamped[bld.ID]
+			if !ok {
+				stamped = now
+				b.stamped[bld.ID] = stamped
+			}
+			end = stamped
+		}
+
+		// Ignore the builds out of the windows, this way the already forgotten
+		// builds will not be tracked again.
+		if now.Sub(end) > b.maxWindow {
+			continue
+		}
+
+		b.finished[bld.ID] = finishedBuild{
+			projectID: bld.ProjectID,
+			succeeded: bld.Status == brigade.StatusSucceeded,
+			end:       end,
+		}
+	}
+
+	for id := range b.stamped {
+		if !present[id] {
+			delete(b.stamped, id)
+		}
+	}
+
+	succeeded := map[string][]float64{}
+	total := map[string][]float64{}
+	for id, fb := range b.finished {
+		age := now.Sub(fb.end)
+		if age > b.maxWindow {
+			delete(b.finished, id)
+			continue
+		}
+
+		if _, ok := total[fb.projectID]; !ok {
+			total[fb.projectID] = make([]float64, len(b.cfg.Windows))
+			succeeded[fb.projectID] = make([]float64, len(b.cfg.Windows))
+		}
+
+		for i, w := range b.cfg.Windows {
+			if age > w {
+				continue
+			}
+			total[fb.projectID][i]++
+			if fb.succeeded {
+				succeeded[fb.projectID][i]++
+			}
+		}
+	}
+
+	ratios := make(map[string][]float64, len(total))
+	for prj, totals := range total {
+		ratios[prj] = make([]float64, len(totals))
+		for i, t := range totals {
+			if t == 0 {
+				ratios[prj][i] = -1
+				continue
+			}
+			ratios[prj][i] = succeeded[prj][i] / t
+		}
+	}
+
+	return ratios
+}
+
+// formatWindow formats a window duration in a short way to be used as label
+// value, for example: 30m, 1h, 7d...
+func formatWindow(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d > day && d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
@@ -0,0 +1,198 @@
+package collector_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const (
+	buildSuccessRatioDesc = `Desc{fqName: "brigade_project_build_success_ratio", help: "Ratio of the finished Brigade builds of the project that succeeded in the window.", constLabels: {}, variableLabels: [project_id window]}`
+)
+
+func TestBuildSuccessRatioSubcollector(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		cfg        collector.BuildSuccessRatioConfig
+		builds     []*brigade.Build
+		expMetrics []metricResult
+	}{
+		{
+			name: "The success ratio should be calculated for every project and window with finished builds.",
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", ProjectID: "prj1", Status: "Succeeded", End: now.Add(-10 * time.Minute)},
+				&brigade.Build{ID: "id2", ProjectID: "prj1", Status: "Failed", End: now.Add(-2 * time.Hour)},
+				&brigade.Build{ID: "id3", ProjectID: "prj1", Status: "Failed", End: now.Add(-48 * time.Hour)},
+				&brigade.Build{ID: "id4", ProjectID: "prj1", Status: "Running"},
+				&brigade.Build{ID: "id5", ProjectID: "prj2", Status: "Succeeded", End: now.Add(-3 * time.Hour)},
+				&brigade.Build{ID: "id6", ProjectID: "prj3", Status: "Failed", End: now.Add(-30 * 24 * time.Hour)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "window": "1h"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "window": "24h"},
+					value:      0.5,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "window": "7d"},
+					value:      1.0 / 3.0,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj2", "window": "24h"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj2", "window": "7d"},
+					value:      1,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+		{
+			name: "The success ratio should be calculated for custom windows.",
+			cfg: collector.BuildSuccessRatioConfig{
+				Windows: []time.Duration{30 * time.Minute},
+			},
+			builds: []*brigade.Build{
+				&brigade.Build{ID: "id1", ProjectID: "prj1", Status: "Succeeded", End: now.Add(-10 * time.Minute)},
+				&brigade.Build{ID: "id2", ProjectID: "prj1", Status: "Failed", End: now.Add(-20 * time.Minute)},
+				&brigade.Build{ID: "id3", ProjectID: "prj1", Status: "Failed", End: now.Add(-40 * time.Minute)},
+			},
+			expMetrics: []metricResult{
+				metricResult{
+					desc:       buildSuccessRatioDesc,
+					labels:     labelMap{"project_id": "prj1", "window": "30m"},
+					value:      0.5,
+					metricType: dto.MetricType_GAUGE,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			mbsvc.On("GetBuilds").Once().Return(test.builds, nil)
+
+			clr := collector.NewBuildSuccessRatio(test.cfg, mbsvc, log.Dummy)
+
+			ch := make(chan prometheus.Metric)
+
+			go func() {
+				clr.Collect(context.TODO(), ch)
+				close(ch)
+			}()
+
+			// Get the metrics
+			var got []metricResult
+			for m := range ch {
+				got = append(got, readMetric(m))
+			}
+
+			// Check metrics are ok.
+			assert.Equal(test.expMetrics, got)
+		})
+	}
+}
+
+func TestBuildSuccessRatioSubcollectorKeepsHistory(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+
+	// Mocks, the second time the failed build has been deleted from Brigade.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Once().Return([]*brigade.Build{
+		&brigade.Build{ID: "id1", ProjectID: "prj1", Status: "Succeeded", End: now.Add(-10 * time.Minute)},
+		&brigade.Build{ID: "id2", ProjectID: "prj1", Status: "Failed", End: now.Add(-20 * time.Minute)},
+	}, nil)
+	mbsvc.On("GetBuilds").Once().Return([]*brigade.Build{
+		&brigade.Build{ID: "id1", ProjectID: "prj1", Status: "Succeeded", End: now.Add(-10 * time.Minute)},
+	}, nil)
+
+	clr := collector.NewBuildSuccessRatio(collector.BuildSuccessRatioConfig{Windows: []time.Duration{time.Hour}}, mbsvc, log.Dummy)
+
+	var got []metricResult
+	for i := 0; i < 2; i++ {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			clr.Collect(context.TODO(), ch)
+			close(ch)
+		}()
+
+		got = got[:0]
+		for m := range ch {
+			got = append(got, readMetric(m))
+		}
+	}
+
+	exp := []metricResult{
+		metricResult{
+			desc:       buildSuccessRatioDesc,
+			labels:     labelMap{"project_id": "prj1", "window": "1h"},
+			value:      0.5,
+			metricType: dto.MetricType_GAUGE,
+		},
+	}
+	assert.Equal(exp, got)
+}
+
+func TestBuildSuccessRatioSubcollectorBuildsWithoutEnd(t *testing.T) {
+	assert := assert.New(t)
+
+	// Mocks, the build without end is always returned by Brigade.
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetBuilds").Return([]*brigade.Build{
+		&brigade.Build{ID: "id1", ProjectID: "prj1", Status: "Succeeded"},
+	}, nil)
+
+	window := 50 * time.Millisecond
+	clr := collector.NewBuildSuccessRatio(collector.BuildSuccessRatioConfig{Windows: []time.Duration{window}}, mbsvc, log.Dummy)
+	collect := func() []metricResult {
+		ch := make(chan prometheus.Metric)
+		go func() {
+			clr.Collect(context.TODO(), ch)
+			close(ch)
+		}()
+
+		var got []metricResult
+		for m := range ch {
+			got = append(got, readMetric(m))
+		}
+		return got
+	}
+
+	// The build ended when it was tracked the first time, so it's out of the window
+	// after the window passes even if it's collected again.
+	assert.Len(collect(), 1)
+	for i := 0; i < 3; i++ {
+		time.Sleep(window / 2)
+		collect()
+	}
+	assert.Empty(collect())
+}
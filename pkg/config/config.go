@@ -0,0 +1,386 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+)
+
+// Config is the configuration of the exporter, the Brigade and push backend settings
+// require a restart, the rest are reloaded.
+type Config struct {
+	// Collectors is the configuration of the collectors.
+	Collectors CollectorsConfig `yaml:"collectors"`
+	// Metrics is the configuration of the metric names and labels.
+	Metrics MetricsConfig `yaml:"metrics"`
+	// StatusPage is the configuration of the status page.
+	StatusPage StatusPageConfig `yaml:"status_page"`
+	// Probe is the configuration of the Brigade installations that can be probed.
+	Probe ProbeConfig `yaml:"probe"`
+
+	// Brigade is the configuration of the Brigade installation, it requires a restart.
+	Brigade BrigadeConfig `yaml:"brigade"`
+	// Pushgateway is the configuration of the push mode, it requires a restart.
+	Pushgateway PushgatewayConfig `yaml:"pushgateway"`
+	// RemoteWrite is the configuration of the remote write, it requires a restart.
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+	// OTLP is the configuration of the OTLP export, it requires a restart.
+	OTLP OTLPConfig `yaml:"otlp"`
+}
+
+// CollectorsConfig is the configuration of the collectors.
+type CollectorsConfig struct {
+	Timeout        Duration            `yaml:"timeout"`
+	Timeouts       map[string]Duration `yaml:"timeouts"`
+	Intervals      map[string]Duration `yaml:"intervals"`
+	MaxSeries      map[string]int      `yaml:"max_series"`
+	Disabled       []string            `yaml:"disabled"`
+	StatusStateSet bool                `yaml:"status_state_set"`
+
+	Projects          ProjectsConfig          `yaml:"projects"`
+	Builds            BuildsConfig            `yaml:"builds"`
+	Jobs              JobsConfig              `yaml:"jobs"`
+	FlakyJobs         FlakyJobsConfig         `yaml:"flaky_jobs"`
+	BuildSuccessRatio BuildSuccessRatioConfig `yaml:"build_success_ratio"`
+	DORA              DORAConfig              `yaml:"dora"`
+}
+
+// ProjectsConfig is the configuration of the projects collector.
+type ProjectsConfig struct {
+	Disabled            bool     `yaml:"disabled"`
+	BlessedWorkerImages []string `yaml:"blessed_worker_images"`
+	DefaultWorkerImage  string   `yaml:"default_worker_image"`
+}
+
+// BuildsConfig is the configuration of the builds collector.
+type BuildsConfig struct {
+	Disabled  bool `yaml:"disabled"`
+	MaxSeries int  `yaml:"max_series"`
+}
+
+// JobsConfig is the configuration of the jobs collector.
+type JobsConfig struct {
+	Disabled  bool `yaml:"disabled"`
+	MaxSeries int  `yaml:"max_series"`
+}
+
+// FlakyJobsConfig is the configuration of the flaky jobs collector.
+type FlakyJobsConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Window  Duration `yaml:"window"`
+}
+
+// BuildSuccessRatioConfig is the configuration of the build success ratio collector.
+type BuildSuccessRatioConfig struct {
+	Enabled bool       `yaml:"enabled"`
+	Windows []Duration `yaml:"windows"`
+}
+
+// DORAConfig is the configuration of the DORA collector.
+type DORAConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	DeployEventTypes []string `yaml:"deploy_event_types"`
+	Window           Duration `yaml:"window"`
+}
+
+// MetricsConfig is the configuration of the metric names and labels.
+type MetricsConfig struct {
+	Namespace     string              `yaml:"namespace"`
+	Prefix        string              `yaml:"prefix"`
+	ConstLabels   map[string]string   `yaml:"const_labels"`
+	RenameLabels  map[string]string   `yaml:"rename_labels"`
+	DropLabels    []string            `yaml:"drop_labels"`
+	AllowedLabels map[string][]string `yaml:"allowed_labels"`
+}
+
+// StatusPageConfig is the configuration of the status page.
+type StatusPageConfig struct {
+	StuckBuildThreshold Duration `yaml:"stuck_build_threshold"`
+}
+
+// ProbeConfig is the configuration of the probe.
+type ProbeConfig struct {
+	Targets []string `yaml:"targets"`
+}
+
+// BrigadeConfig is the configuration of the Brigade installation.
+type BrigadeConfig struct {
+	Namespace   string `yaml:"namespace"`
+	KubeConfig  string `yaml:"kubeconfig"`
+	Development bool   `yaml:"development"`
+	Fake        bool   `yaml:"fake"`
+}
+
+// PushgatewayConfig is the configuration of the push mode, disabled without URL.
+type PushgatewayConfig struct {
+	URL      string            `yaml:"url"`
+	Job      string            `yaml:"job"`
+	Grouping map[string]string `yaml:"grouping"`
+	Interval Duration          `yaml:"interval"`
+	Username string            `yaml:"username"`
+	Password string            `yaml:"password"`
+}
+
+// RemoteWriteConfig is the configuration of the remote write, disabled without URL.
+type RemoteWriteConfig struct {
+	URL            string            `yaml:"url"`
+	Interval       Duration          `yaml:"interval"`
+	Timeout        Duration          `yaml:"timeout"`
+	BatchSize      int               `yaml:"batch_size"`
+	QueueSize      int               `yaml:"queue_size"`
+	MaxRetries     int               `yaml:"max_retries"`
+	ExternalLabels map[string]string `yaml:"external_labels"`
+	Username       string            `yaml:"username"`
+	Password       string            `yaml:"password"`
+}
+
+// OTLPConfig is the configuration of the OTLP export, disabled without endpoint.
+type OTLPConfig struct {
+	Endpoint           string            `yaml:"endpoint"`
+	Interval           Duration          `yaml:"interval"`
+	Timeout            Duration          `yaml:"timeout"`
+	Headers            map[string]string `yaml:"headers"`
+	Cluster            string            `yaml:"cluster"`
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+}
+
+// Load loads the configuration from a YAML file on top of the base configuration,
+// the settings that are not on the file will have the base configuration values.
+func Load(path string, base Config) (Config, error) {
+	cfg := base.clone()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("could not read configuration file: %s", err)
+	}
+
+	if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse configuration file: %s", err)
+	}
+
+	return cfg, nil
+}
+
+// KeepRestartSettings returns the configuration with the settings that require a
+// restart of the running configuration, and the sections of them that changed so
+// they can be reported.
+func (c Config) KeepRestartSettings(running Config) (Config, []string) {
+	var changed []string
+	for _, s := range []struct {
+		name        string
+		cfg, runCfg interface{}
+	}{
+		{name: "brigade", cfg: c.Brigade, runCfg: running.Brigade},
+		{name: "pushgateway", cfg: c.Pushgateway, runCfg: running.Pushgateway},
+		{name: "remote_write", cfg: c.RemoteWrite, runCfg: running.RemoteWrite},
+		{name: "otlp", cfg: c.OTLP, runCfg: running.OTLP},
+	} {
+		if !reflect.DeepEqual(s.cfg, s.runCfg) {
+			changed = append(changed, s.name)
+		}
+	}
+
+	c.Brigade = running.Brigade
+	c.Pushgateway = running.Pushgateway
+	c.RemoteWrite = running.RemoteWrite
+	c.OTLP = running.OTLP
+	return c, changed
+}
+
+// clone returns a copy of the configuration that doesn't share the maps, loading
+// a file merges the maps of the file with the existing ones.
+func (c Config) clone() Config {
+	c.Collectors.Timeouts = cloneDurations(c.Collectors.Timeouts)
+	c.Collectors.Intervals = cloneDurations(c.Collectors.Intervals)
+	if c.Collectors.MaxSeries != nil {
+		maxSeries := make(map[string]int, len(c.Collectors.MaxSeries))
+		for k, v := range c.Collectors.MaxSeries {
+			maxSeries[k] = v
+		}
+		c.Collectors.MaxSeries = maxSeries
+	}
+	c.Metrics.ConstLabels = cloneStrings(c.Metrics.ConstLabels)
+	c.Metrics.RenameLabels = cloneStrings(c.Metrics.RenameLabels)
+	if c.Metrics.AllowedLabels != nil {
+		allowed := make(map[string][]string, len(c.Metrics.AllowedLabels))
+		for k, v := range c.Metrics.AllowedLabels {
+			allowed[k] = v
+		}
+		c.Metrics.AllowedLabels = allowed
+	}
+	c.Pushgateway.Grouping = cloneStrings(c.Pushgateway.Grouping)
+	c.RemoteWrite.ExternalLabels = cloneStrings(c.RemoteWrite.ExternalLabels)
+	c.OTLP.Headers = cloneStrings(c.OTLP.Headers)
+	c.OTLP.ResourceAttributes = cloneStrings(c.OTLP.ResourceAttributes)
+	return c
+}
+
+func cloneDurations(m map[string]Duration) map[string]Duration {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]Duration, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+func cloneStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// Validate will check the configuration is valid.
+func (c Config) Validate() error {
+	cc := c.Collectors
+	if cc.Timeout < 0 {
+		return fmt.Errorf("invalid negative collectors timeout")
+	}
+	for name, d := range cc.Timeouts {
+		if d < 0 {
+			return fmt.Errorf("invalid negative %s collector timeout", name)
+		}
+	}
+	for name, d := range cc.Intervals {
+		if d < 0 {
+			return fmt.Errorf("invalid negative %s collector interval", name)
+		}
+	}
+	if cc.Builds.MaxSeries < 0 || cc.Jobs.MaxSeries < 0 {
+		return fmt.Errorf("invalid negative max series")
+	}
+	for name, max := range cc.MaxSeries {
+		if max < 0 {
+			return fmt.Errorf("invalid negative %s collector max series", name)
+		}
+	}
+	if cc.FlakyJobs.Window < 0 || cc.DORA.Window < 0 {
+		return fmt.Errorf("invalid negative window")
+	}
+	for _, w := range cc.BuildSuccessRatio.Windows {
+		if w <= 0 {
+			return fmt.Errorf("invalid build success ratio window %s", w)
+		}
+	}
+	if c.StatusPage.StuckBuildThreshold < 0 {
+		return fmt.Errorf("invalid negative stuck build threshold")
+	}
+	if c.Pushgateway.Interval < 0 || c.RemoteWrite.Interval < 0 || c.OTLP.Interval < 0 {
+		return fmt.Errorf("invalid negative push interval")
+	}
+	if c.RemoteWrite.Timeout < 0 || c.OTLP.Timeout < 0 {
+		return fmt.Errorf("invalid negative push timeout")
+	}
+
+	return c.ExporterConfig().Metrics.Validate()
+}
+
+// ExporterConfig returns the exporter configuration.
+func (c Config) ExporterConfig() collector.Config {
+	cc := c.Collectors
+	windows := make([]time.Duration, len(cc.BuildSuccessRatio.Windows))
+	for i, w := range cc.BuildSuccessRatio.Windows {
+		windows[i] = time.Duration(w)
+	}
+	if len(windows) == 0 {
+		windows = nil
+	}
+
+	return collector.Config{
+		Metrics: collector.MetricsConfig{
+			Namespace:     c.Metrics.Namespace,
+			Prefix:        c.Metrics.Prefix,
+			ConstLabels:   c.Metrics.ConstLabels,
+			RenameLabels:  c.Metrics.RenameLabels,
+			DropLabels:    c.Metrics.DropLabels,
+			AllowedLabels: c.Metrics.AllowedLabels,
+		},
+
+		CollectTimeout:        time.Duration(cc.Timeout),
+		SubcollectorTimeouts:  durations(cc.Timeouts),
+		SubcollectorIntervals: durations(cc.Intervals),
+		SubcollectorMaxSeries: cc.MaxSeries,
+		DisabledSubcollectors: cc.Disabled,
+
+		DisableProjects:     cc.Projects.Disabled,
+		BlessedWorkerImages: cc.Projects.BlessedWorkerImages,
+		DefaultWorkerImage:  cc.Projects.DefaultWorkerImage,
+		DisableBuilds:       cc.Builds.Disabled,
+		BuildsMaxSeries:     cc.Builds.MaxSeries,
+		DisableJobs:         cc.Jobs.Disabled,
+		JobsMaxSeries:       cc.Jobs.MaxSeries,
+		StatusStateSet:      cc.StatusStateSet,
+		EnableFlakyJobs:     cc.FlakyJobs.Enabled,
+		FlakyJobWindow:      time.Duration(cc.FlakyJobs.Window),
+
+		EnableBuildSuccessRatio:  cc.BuildSuccessRatio.Enabled,
+		BuildSuccessRatioWindows: windows,
+
+		EnableDORA:           cc.DORA.Enabled,
+		DORADeployEventTypes: cc.DORA.DeployEventTypes,
+		DORAWindow:           time.Duration(cc.DORA.Window),
+	}
+}
+
+func durations(m map[string]Duration) map[string]time.Duration {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]time.Duration, len(m))
+	for k, v := range m {
+		res[k] = time.Duration(v)
+	}
+	return res
+}
+
+// Duration is a duration that can be unmarshaled from YAML, apart from the
+// regular duration units it accepts days (e.g: 7d).
+type Duration time.Duration
+
+// String satisfies fmt.Stringer.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalYAML satisfies yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	dd, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dd)
+	return nil
+}
+
+// ParseDuration parses a duration, apart from the regular duration units it accepts days.
+func ParseDuration(v string) (time.Duration, error) {
+	// Days are not supported by the standard library.
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %s", v, err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(v)
+}
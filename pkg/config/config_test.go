@@ -0,0 +1,296 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/config"
+)
+
+// writeFile writes a temporary file with the data and returns its path.
+func writeFile(t *testing.T, dir, data string) string {
+	path := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   config.Config
+		file   string
+		expCfg config.Config
+		expErr bool
+	}{
+		{
+			name: "The file settings should override the base ones and keep the rest.",
+			base: config.Config{
+				Collectors: config.CollectorsConfig{
+					Timeout:   config.Duration(10 * time.Second),
+					Intervals: map[string]config.Duration{"projects": config.Duration(time.Minute)},
+					FlakyJobs: config.FlakyJobsConfig{Window: config.Duration(24 * time.Hour)},
+				},
+				Metrics: config.MetricsConfig{Namespace: "brigade"},
+			},
+			file: `
+collectors:
+  timeout: 30s
+  intervals:
+    jobs: 1m
+  disabled: [dora]
+  builds:
+    max_series: 1000
+  build_success_ratio:
+    enabled: true
+    windows: [1h, 7d]
+metrics:
+  const_labels:
+    cluster: prod-1
+probe:
+  targets: [brigade-team1]
+`,
+			expCfg: config.Config{
+				Collectors: config.CollectorsConfig{
+					Timeout: config.Duration(30 * time.Second),
+					Intervals: map[string]config.Duration{
+						"projects": config.Duration(time.Minute),
+						"jobs":     config.Duration(time.Minute),
+					},
+					Disabled:  []string{"dora"},
+					Builds:    config.BuildsConfig{MaxSeries: 1000},
+					FlakyJobs: config.FlakyJobsConfig{Window: config.Duration(24 * time.Hour)},
+					BuildSuccessRatio: config.BuildSuccessRatioConfig{
+						Enabled: true,
+						Windows: []config.Duration{config.Duration(time.Hour), config.Duration(7 * 24 * time.Hour)},
+					},
+				},
+				Metrics: config.MetricsConfig{
+					Namespace:   "brigade",
+					ConstLabels: map[string]string{"cluster": "prod-1"},
+				},
+				Probe: config.ProbeConfig{Targets: []string{"brigade-team1"}},
+			},
+		},
+		{
+			name:   "Unknown settings should error.",
+			file:   "collectors:\n  unknown: true\n",
+			expErr: true,
+		},
+		{
+			name:   "Invalid durations should error.",
+			file:   "collectors:\n  timeout: 10x\n",
+			expErr: true,
+		},
+		{
+			name: "The Brigade and push backend settings should be loaded.",
+			base: config.Config{
+				RemoteWrite: config.RemoteWriteConfig{Interval: config.Duration(30 * time.Second), BatchSize: 500},
+			},
+			file: `
+brigade:
+  namespace: brigade
+pushgateway:
+  url: http://pushgateway:9091
+  grouping:
+    cluster: prod-1
+remote_write:
+  url: http://prometheus:9090/api/v1/write
+  timeout: 5s
+otlp:
+  endpoint: http://otel-collector:4318/v1/metrics
+  resource_attributes:
+    deployment.environment: production
+`,
+			expCfg: config.Config{
+				Brigade: config.BrigadeConfig{Namespace: "brigade"},
+				Pushgateway: config.PushgatewayConfig{
+					URL:      "http://pushgateway:9091",
+					Grouping: map[string]string{"cluster": "prod-1"},
+				},
+				RemoteWrite: config.RemoteWriteConfig{
+					URL:       "http://prometheus:9090/api/v1/write",
+					Interval:  config.Duration(30 * time.Second),
+					Timeout:   config.Duration(5 * time.Second),
+					BatchSize: 500,
+				},
+				OTLP: config.OTLPConfig{
+					Endpoint:           "http://otel-collector:4318/v1/metrics",
+					ResourceAttributes: map[string]string{"deployment.environment": "production"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir, err := ioutil.TempDir("", "config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := writeFile(t, dir, test.file)
+
+			baseIntervals := len(test.base.Collectors.Intervals)
+			cfg, err := config.Load(path, test.base)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expCfg, cfg)
+			}
+
+			// The base configuration shouldn't be modified.
+			assert.Len(test.base.Collectors.Intervals, baseIntervals)
+		})
+	}
+}
+
+func TestKeepRestartSettings(t *testing.T) {
+	running := config.Config{
+		Brigade:     config.BrigadeConfig{Namespace: "brigade"},
+		Pushgateway: config.PushgatewayConfig{URL: "http://pushgateway:9091", Grouping: map[string]string{"cluster": "prod-1"}},
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		expCfg     config.Config
+		expChanged []string
+	}{
+		{
+			name: "Without changes of the restart settings the configuration should be the same.",
+			cfg: config.Config{
+				Collectors:  config.CollectorsConfig{Timeout: config.Duration(time.Minute)},
+				Brigade:     config.BrigadeConfig{Namespace: "brigade"},
+				Pushgateway: config.PushgatewayConfig{URL: "http://pushgateway:9091", Grouping: map[string]string{"cluster": "prod-1"}},
+			},
+			expCfg: config.Config{
+				Collectors:  config.CollectorsConfig{Timeout: config.Duration(time.Minute)},
+				Brigade:     config.BrigadeConfig{Namespace: "brigade"},
+				Pushgateway: config.PushgatewayConfig{URL: "http://pushgateway:9091", Grouping: map[string]string{"cluster": "prod-1"}},
+			},
+		},
+		{
+			name: "The changed restart settings should be reported and keep the running ones.",
+			cfg: config.Config{
+				Collectors:  config.CollectorsConfig{Timeout: config.Duration(time.Minute)},
+				Brigade:     config.BrigadeConfig{Namespace: "brigade-ci"},
+				Pushgateway: config.PushgatewayConfig{URL: "http://pushgateway:9091", Grouping: map[string]string{"cluster": "prod-2"}},
+				OTLP:        config.OTLPConfig{Endpoint: "http://otel-collector:4318/v1/metrics"},
+			},
+			expCfg: config.Config{
+				Collectors:  config.CollectorsConfig{Timeout: config.Duration(time.Minute)},
+				Brigade:     config.BrigadeConfig{Namespace: "brigade"},
+				Pushgateway: config.PushgatewayConfig{URL: "http://pushgateway:9091", Grouping: map[string]string{"cluster": "prod-1"}},
+			},
+			expChanged: []string{"brigade", "pushgateway", "otlp"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cfg, changed := test.cfg.KeepRestartSettings(running)
+			assert.Equal(test.expCfg, cfg)
+			assert.Equal(test.expChanged, changed)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.Config
+		expErr bool
+	}{
+		{
+			name: "An empty configuration should be valid.",
+			cfg:  config.Config{},
+		},
+		{
+			name:   "Negative timeouts should be invalid.",
+			cfg:    config.Config{Collectors: config.CollectorsConfig{Timeouts: map[string]config.Duration{"jobs": -1}}},
+			expErr: true,
+		},
+		{
+			name:   "Negative max series should be invalid.",
+			cfg:    config.Config{Collectors: config.CollectorsConfig{Jobs: config.JobsConfig{MaxSeries: -1}}},
+			expErr: true,
+		},
+		{
+			name:   "Negative collector max series should be invalid.",
+			cfg:    config.Config{Collectors: config.CollectorsConfig{MaxSeries: map[string]int{"projects": -1}}},
+			expErr: true,
+		},
+		{
+			name:   "Empty build success ratio windows should be invalid.",
+			cfg:    config.Config{Collectors: config.CollectorsConfig{BuildSuccessRatio: config.BuildSuccessRatioConfig{Windows: []config.Duration{0}}}},
+			expErr: true,
+		},
+		{
+			name:   "Negative push intervals should be invalid.",
+			cfg:    config.Config{OTLP: config.OTLPConfig{Interval: config.Duration(-time.Second)}},
+			expErr: true,
+		},
+		{
+			name:   "Invalid metric settings should be invalid.",
+			cfg:    config.Config{Metrics: config.MetricsConfig{ConstLabels: map[string]string{"cluster-name": "prod"}}},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := test.cfg.Validate()
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestExporterConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := config.Config{
+		Collectors: config.CollectorsConfig{
+			Timeout:   config.Duration(time.Second),
+			Timeouts:  map[string]config.Duration{"jobs": config.Duration(time.Minute)},
+			MaxSeries: map[string]int{"projects": 1000},
+			Disabled:  []string{"dora"},
+			Projects:  config.ProjectsConfig{Disabled: true, BlessedWorkerImages: []string{"brigade-worker:v1"}},
+			Jobs:      config.JobsConfig{MaxSeries: 10},
+			DORA:      config.DORAConfig{Enabled: true, DeployEventTypes: []string{"deploy"}, Window: config.Duration(time.Hour)},
+		},
+		Metrics: config.MetricsConfig{Prefix: "team_"},
+	}
+
+	exp := collector.Config{
+		Metrics:               collector.MetricsConfig{Prefix: "team_"},
+		CollectTimeout:        time.Second,
+		SubcollectorTimeouts:  map[string]time.Duration{"jobs": time.Minute},
+		SubcollectorMaxSeries: map[string]int{"projects": 1000},
+		DisabledSubcollectors: []string{"dora"},
+		DisableProjects:       true,
+		BlessedWorkerImages:   []string{"brigade-worker:v1"},
+		JobsMaxSeries:         10,
+		EnableDORA:            true,
+		DORADeployEventTypes:  []string{"deploy"},
+		DORAWindow:            time.Hour,
+	}
+	assert.Equal(exp, cfg.ExporterConfig())
+}
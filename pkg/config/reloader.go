@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+const (
+	reloaderNamespaceDef = "brigade"
+	reloaderSubsystem    = "exporter_config"
+)
+
+// LoadFunc loads the configuration.
+type LoadFunc func() (Config, error)
+
+// ApplyFunc applies a new valid configuration.
+type ApplyFunc func(Config) error
+
+// Reloader reloads the configuration and applies it only if it's valid.
+type Reloader struct {
+	reloads        *prometheus.CounterVec
+	lastSuccessful prometheus.Gauge
+	lastSuccess    prometheus.Gauge
+
+	mu     sync.Mutex
+	load   LoadFunc
+	apply  ApplyFunc
+	logger log.Logger
+}
+
+// NewReloader returns a new configuration reloader that measures the reloads,
+// the metrics have the namespace, by default brigade.
+func NewReloader(namespace string, reg prometheus.Registerer, load LoadFunc, apply ApplyFunc, logger log.Logger) (*Reloader, error) {
+	if namespace == "" {
+		namespace = reloaderNamespaceDef
+	}
+
+	r := &Reloader{
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "reloads_total",
+			Help:      "Number of configuration reloads.",
+		}, []string{"success"}),
+
+		lastSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "last_reload_successful",
+			Help:      "Whether the last configuration reload succeeded.",
+		}),
+
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: reloaderSubsystem,
+			Name:      "last_reload_success_timestamp_seconds",
+			Help:      "Timestamp of the last successful configuration reload.",
+		}),
+
+		load:   load,
+		apply:  apply,
+		logger: logger,
+	}
+
+	for _, c := range []prometheus.Collector{r.reloads, r.lastSuccessful, r.lastSuccess} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Reload loads the configuration, validates it and applies it. If the configuration
+// is not valid the current configuration is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reload()
+	if err != nil {
+		r.logger.Errorf("configuration reload failed, keeping the current configuration: %s", err)
+		r.reloads.WithLabelValues("false").Inc()
+		r.lastSuccessful.Set(0)
+		return err
+	}
+
+	r.logger.Infof("configuration reloaded")
+	r.reloads.WithLabelValues("true").Inc()
+	r.lastSuccessful.Set(1)
+	r.lastSuccess.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+func (r *Reloader) reload() error {
+	cfg, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	return r.apply(cfg)
+}
+
+// Watch reloads the configuration every time the file changes, the file is checked
+// on every interval until stop is closed. The optional heartbeat is called after
+// every check.
+func (r *Reloader) Watch(path string, interval time.Duration, heartbeat func(), stop <-chan struct{}) {
+	if heartbeat == nil {
+		heartbeat = func() {}
+	}
+
+	last := modTime(path)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			mt := modTime(path)
+			if !mt.Equal(last) {
+				last = mt
+				r.logger.Infof("configuration file %s changed", path)
+				r.Reload()
+			}
+			heartbeat()
+		}
+	}
+}
+
+// modTime returns the modification time of the file, zero if it can't be read.
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/config"
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+func TestReloader(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		loadErr    error
+		applyErr   error
+		expErr     bool
+		expApplied bool
+		expMetrics []string
+	}{
+		{
+			name:       "A valid configuration should be applied.",
+			cfg:        config.Config{Metrics: config.MetricsConfig{Prefix: "team_"}},
+			expApplied: true,
+			expMetrics: []string{
+				`brigade_exporter_config_reloads_total{success="true"} 1`,
+				`brigade_exporter_config_last_reload_successful 1`,
+			},
+		},
+		{
+			name:    "A configuration that can't be loaded shouldn't be applied.",
+			loadErr: fmt.Errorf("wanted error"),
+			expErr:  true,
+			expMetrics: []string{
+				`brigade_exporter_config_reloads_total{success="false"} 1`,
+				`brigade_exporter_config_last_reload_successful 0`,
+				`brigade_exporter_config_last_reload_success_timestamp_seconds 0`,
+			},
+		},
+		{
+			name:   "An invalid configuration shouldn't be applied.",
+			cfg:    config.Config{Metrics: config.MetricsConfig{Prefix: "team-"}},
+			expErr: true,
+			expMetrics: []string{
+				`brigade_exporter_config_reloads_total{success="false"} 1`,
+				`brigade_exporter_config_last_reload_successful 0`,
+			},
+		},
+		{
+			name:       "A configuration that fails when applying should fail.",
+			applyErr:   fmt.Errorf("wanted error"),
+			expErr:     true,
+			expApplied: true,
+			expMetrics: []string{
+				`brigade_exporter_config_reloads_total{success="false"} 1`,
+				`brigade_exporter_config_last_reload_successful 0`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			applied := false
+			load := func() (config.Config, error) { return test.cfg, test.loadErr }
+			apply := func(config.Config) error {
+				applied = true
+				return test.applyErr
+			}
+
+			promReg := prometheus.NewRegistry()
+			r, err := config.NewReloader("", promReg, load, apply, log.Dummy)
+			if !assert.NoError(err) {
+				return
+			}
+			err = r.Reload()
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(test.expApplied, applied)
+
+			rec := httptest.NewRecorder()
+			promhttp.HandlerFor(promReg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+			for _, expMetric := range test.expMetrics {
+				assert.Contains(rec.Body.String(), expMetric)
+			}
+		})
+	}
+}
+
+func TestReloaderWatch(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "metrics:\n  prefix: team1_\n")
+
+	applied := make(chan config.Config, 1)
+	load := func() (config.Config, error) { return config.Load(path, config.Config{}) }
+	apply := func(cfg config.Config) error {
+		select {
+		case applied <- cfg:
+		default:
+		}
+		return nil
+	}
+	r, err := config.NewReloader("", prometheus.NewRegistry(), load, apply, log.Dummy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beats := make(chan struct{}, 1)
+	heartbeat := func() {
+		select {
+		case beats <- struct{}{}:
+		default:
+		}
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go r.Watch(path, 10*time.Millisecond, heartbeat, stop)
+
+	// The watcher beats on every check.
+	select {
+	case <-beats:
+	case <-time.After(5 * time.Second):
+		assert.Fail("watcher didn't beat")
+		return
+	}
+
+	// Change the file, the modification time is changed until the watcher
+	// notices it because we don't know when the watcher has started.
+	writeFile(t, dir, "metrics:\n  prefix: team2_\n")
+	timeout := time.After(5 * time.Second)
+	for i := 1; ; i++ {
+		mt := time.Now().Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, mt, mt)
+
+		select {
+		case cfg := <-applied:
+			assert.Equal("team2_", cfg.Metrics.Prefix)
+			return
+		case <-timeout:
+			assert.Fail("configuration not reloaded after the file changed")
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package gather
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Shared is a prometheus.Gatherer that shares the gatherings of a gatherer between
+// its users, like the push backends that gather the same registry on their own
+// intervals. A gathering made before the max age passes gets the metrics of the
+// last one, and the gatherings made while another one is in progress wait for it,
+// so the registry (and Brigade) is only gathered once per max age.
+//
+// The returned metric families are shared, the users must not modify them.
+type Shared struct {
+	mu       sync.Mutex
+	gatherer prometheus.Gatherer
+	maxAge   time.Duration
+	mfs      []*dto.MetricFamily
+	err      error
+	gathered time.Time
+}
+
+// NewShared returns a new Shared gatherer.
+func NewShared(g prometheus.Gatherer, maxAge time.Duration) *Shared {
+	return &Shared{
+		gatherer: g,
+		maxAge:   maxAge,
+	}
+}
+
+// Gather satisfies prometheus.Gatherer.
+func (s *Shared) Gather() ([]*dto.MetricFamily, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.gathered.IsZero() && time.Since(s.gathered) < s.maxAge {
+		return s.mfs, s.err
+	}
+
+	// The gatherer returns the metrics it could gather even on errors.
+	s.mfs, s.err = s.gatherer.Gather()
+	s.gathered = time.Now()
+	return s.mfs, s.err
+}
@@ -0,0 +1,92 @@
+package gather_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/gather"
+)
+
+// countGatherer is a gatherer that counts the gatherings.
+type countGatherer struct {
+	mu    sync.Mutex
+	count int
+	err   error
+}
+
+func (c *countGatherer) Gather() ([]*dto.MetricFamily, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	name := fmt.Sprintf("gathering_%d", c.count)
+	return []*dto.MetricFamily{{Name: &name}}, c.err
+}
+
+func (c *countGatherer) gatherings() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+func TestShared(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxAge        time.Duration
+		err           error
+		expGatherings int
+		expName       string
+	}{
+		{
+			name:          "The gatherings before the max age should share the last one.",
+			maxAge:        time.Hour,
+			expGatherings: 1,
+			expName:       "gathering_1",
+		},
+		{
+			name:          "The gathering errors should be shared.",
+			maxAge:        time.Hour,
+			err:           fmt.Errorf("wanted error"),
+			expGatherings: 1,
+			expName:       "gathering_1",
+		},
+		{
+			name:          "Without max age every gathering should gather.",
+			expGatherings: 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := &countGatherer{err: test.err}
+			s := gather.NewShared(g, test.maxAge)
+
+			// Gather concurrently like the push backends.
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					mfs, err := s.Gather()
+					assert.Equal(test.err, err)
+					assert.Len(mfs, 1)
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(test.expGatherings, g.gatherings())
+			if test.expName != "" {
+				mfs, _ := s.Gather()
+				if assert.Len(mfs, 1) {
+					assert.Equal(test.expName, mfs[0].GetName())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Defaults.
+	maxMissedDef = 3
+)
+
+// loop is a loop that beats on every interval.
+type loop struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// Checker checks the liveness of the loops of the exporter (e.g: the configuration
+// watcher or the push backends) with their heartbeats, a loop that doesn't beat for
+// some of its intervals has stalled.
+type Checker struct {
+	mu        sync.Mutex
+	loops     map[string]*loop
+	maxMissed int
+}
+
+// NewChecker returns a new Checker, the loops stall after missing maxMissed
+// heartbeats, by default 3.
+func NewChecker(maxMissed int) *Checker {
+	if maxMissed <= 0 {
+		maxMissed = maxMissedDef
+	}
+
+	return &Checker{
+		loops:     map[string]*loop{},
+		maxMissed: maxMissed,
+	}
+}
+
+// Register registers a loop that runs every interval and returns the heartbeat the
+// loop needs to call on every iteration. The loop is alive until it misses the
+// first heartbeats, the loops without interval are not checked.
+func (c *Checker) Register(name string, interval time.Duration) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := &loop{interval: interval, last: time.Now()}
+	if interval > 0 {
+		c.loops[name] = l
+	}
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		l.last = time.Now()
+	}
+}
+
+// Check returns an error with the loops that have stalled.
+func (c *Checker) Check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var stalled []string
+	for name, l := range c.loops {
+		if since := time.Since(l.last); since > time.Duration(c.maxMissed)*l.interval {
+			stalled = append(stalled, fmt.Sprintf("%s (last heartbeat %s ago)", name, since.Truncate(time.Millisecond)))
+		}
+	}
+	if len(stalled) == 0 {
+		return nil
+	}
+
+	sort.Strings(stalled)
+	return fmt.Errorf("stalled loops: %s", strings.Join(stalled, ", "))
+}
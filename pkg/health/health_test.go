@@ -0,0 +1,78 @@
+package health_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/health"
+)
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name      string
+		beat      bool
+		wait      time.Duration
+		expErr    bool
+		expErrMsg string
+	}{
+		{
+			name: "A registered loop that didn't miss the heartbeats should be alive.",
+		},
+		{
+			name: "A loop that beats should be alive.",
+			beat: true,
+			wait: 50 * time.Millisecond,
+		},
+		{
+			name:      "A loop that misses the heartbeats should stall.",
+			wait:      50 * time.Millisecond,
+			expErr:    true,
+			expErrMsg: "stalled loops: pusher",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			c := health.NewChecker(2)
+			c.Register("watcher", time.Hour)
+			beat := c.Register("pusher", 10*time.Millisecond)
+
+			// Beat on every interval while waiting.
+			deadline := time.Now().Add(test.wait)
+			for time.Now().Before(deadline) {
+				if test.beat {
+					beat()
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			err := c.Check()
+			if test.expErr {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), test.expErrMsg)
+					assert.NotContains(err.Error(), "watcher")
+				}
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestCheckerRecovers(t *testing.T) {
+	assert := assert.New(t)
+
+	c := health.NewChecker(0)
+	beat := c.Register("otlp", 5*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Error(c.Check())
+
+	// A stalled loop that beats again is alive.
+	beat()
+	assert.NoError(c.Check())
+}
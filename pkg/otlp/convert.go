@@ -0,0 +1,165 @@
+package otlp
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// metrics returns the OTLP metrics of the Prometheus metric families. The counters are
+// mapped to monotonic cumulative sums, the gauges and untyped metrics to gauges, and
+// the histograms and summaries to their OTLP equivalents. The values that are not
+// finite are skipped because they can't be encoded in JSON.
+func metrics(mfs []*dto.MetricFamily, start, now int64) []Metric {
+	startNano := strconv.FormatInt(start, 10)
+	nowNano := strconv.FormatInt(now, 10)
+
+	res := make([]Metric, 0, len(mfs))
+	for _, mf := range mfs {
+		m := Metric{
+			Name:        mf.GetName(),
+			Description: mf.GetHelp(),
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			m.Sum = &Sum{
+				AggregationTemporality: aggregationTemporalityCumulative,
+				IsMonotonic:            true,
+			}
+			for _, pm := range mf.GetMetric() {
+				if v := pm.GetCounter().GetValue(); finite(v) {
+					m.Sum.DataPoints = append(m.Sum.DataPoints, NumberDataPoint{
+						Attributes:        attributes(pm.GetLabel()),
+						StartTimeUnixNano: startNano,
+						TimeUnixNano:      timestamp(pm, nowNano),
+						AsDouble:          v,
+					})
+				}
+			}
+			if len(m.Sum.DataPoints) == 0 {
+				continue
+			}
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			m.Gauge = &Gauge{}
+			for _, pm := range mf.GetMetric() {
+				v := pm.GetGauge().GetValue()
+				if mf.GetType() == dto.MetricType_UNTYPED {
+					v = pm.GetUntyped().GetValue()
+				}
+				if finite(v) {
+					m.Gauge.DataPoints = append(m.Gauge.DataPoints, NumberDataPoint{
+						Attributes:   attributes(pm.GetLabel()),
+						TimeUnixNano: timestamp(pm, nowNano),
+						AsDouble:     v,
+					})
+				}
+			}
+			if len(m.Gauge.DataPoints) == 0 {
+				continue
+			}
+		case dto.MetricType_HISTOGRAM:
+			m.Histogram = &Histogram{AggregationTemporality: aggregationTemporalityCumulative}
+			for _, pm := range mf.GetMetric() {
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramDataPoint(pm, startNano, nowNano))
+			}
+		case dto.MetricType_SUMMARY:
+			m.Summary = &Summary{}
+			for _, pm := range mf.GetMetric() {
+				s := pm.GetSummary()
+				dp := SummaryDataPoint{
+					Attributes:        attributes(pm.GetLabel()),
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      timestamp(pm, nowNano),
+					Count:             strconv.FormatUint(s.GetSampleCount(), 10),
+					Sum:               finiteOrZero(s.GetSampleSum()),
+				}
+				for _, q := range s.GetQuantile() {
+					if finite(q.GetValue()) {
+						dp.QuantileValues = append(dp.QuantileValues, QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+					}
+				}
+				m.Summary.DataPoints = append(m.Summary.DataPoints, dp)
+			}
+		default:
+			continue
+		}
+
+		res = append(res, m)
+	}
+
+	return res
+}
+
+// histogramDataPoint returns the histogram data point of a Prometheus histogram, the
+// Prometheus cumulative buckets are converted to the OTLP per bucket counts.
+func histogramDataPoint(pm *dto.Metric, startNano, nowNano string) HistogramDataPoint {
+	h := pm.GetHistogram()
+	dp := HistogramDataPoint{
+		Attributes:        attributes(pm.GetLabel()),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      timestamp(pm, nowNano),
+		Count:             strconv.FormatUint(h.GetSampleCount(), 10),
+		Sum:               finiteOrZero(h.GetSampleSum()),
+		BucketCounts:      []string{},
+		ExplicitBounds:    []float64{},
+	}
+
+	var prev uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), +1) {
+			continue
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, b.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, strconv.FormatUint(b.GetCumulativeCount()-prev, 10))
+		prev = b.GetCumulativeCount()
+	}
+	// The +Inf bucket.
+	dp.BucketCounts = append(dp.BucketCounts, strconv.FormatUint(h.GetSampleCount()-prev, 10))
+
+	return dp
+}
+
+// attributes returns the labels as attributes sorted by key, the labels with an empty
+// value are dropped like Prometheus does.
+func attributes(labels []*dto.LabelPair) []KeyValue {
+	res := make([]KeyValue, 0, len(labels))
+	for _, l := range labels {
+		if l.GetValue() != "" {
+			res = append(res, KeyValue{Key: l.GetName(), Value: AnyValue{StringValue: l.GetValue()}})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// resourceAttributes returns the attributes of the map sorted by key.
+func resourceAttributes(attrs map[string]string) []KeyValue {
+	res := make([]KeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		res = append(res, KeyValue{Key: k, Value: AnyValue{StringValue: v}})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// timestamp returns the metric timestamp in nanoseconds, or now if the metric doesn't have it.
+func timestamp(pm *dto.Metric, nowNano string) string {
+	if pm.TimestampMs == nil {
+		return nowNano
+	}
+	return strconv.FormatInt(pm.GetTimestampMs()*1e6, 10)
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func finiteOrZero(f float64) float64 {
+	if !finite(f) {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,116 @@
+package otlp
+
+// The OTLP metrics messages in the OTLP/HTTP JSON encoding, only the fields required
+// to export the Prometheus metrics. The 64 bit integers are encoded as strings and
+// the enums as integers as the OTLP JSON encoding requires.
+
+// aggregationTemporalityCumulative is the cumulative aggregation temporality.
+const aggregationTemporalityCumulative = 2
+
+// ExportMetricsServiceRequest is the OTLP metrics export request.
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []ResourceMetrics `json:"resourceMetrics"`
+}
+
+// ResourceMetrics are the metrics of a resource.
+type ResourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []ScopeMetrics `json:"scopeMetrics"`
+}
+
+// Resource is the entity that produces the metrics.
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeMetrics are the metrics of an instrumentation scope.
+type ScopeMetrics struct {
+	Scope   InstrumentationScope `json:"scope"`
+	Metrics []Metric             `json:"metrics"`
+}
+
+// InstrumentationScope is the library that produces the metrics.
+type InstrumentationScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// Metric is a metric, only one of the data fields is set.
+type Metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Gauge       *Gauge     `json:"gauge,omitempty"`
+	Sum         *Sum       `json:"sum,omitempty"`
+	Histogram   *Histogram `json:"histogram,omitempty"`
+	Summary     *Summary   `json:"summary,omitempty"`
+}
+
+// Gauge is a gauge metric.
+type Gauge struct {
+	DataPoints []NumberDataPoint `json:"dataPoints"`
+}
+
+// Sum is a sum metric, the Prometheus counters are monotonic cumulative sums.
+type Sum struct {
+	DataPoints             []NumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+// Histogram is a histogram metric.
+type Histogram struct {
+	DataPoints             []HistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+// Summary is a summary metric.
+type Summary struct {
+	DataPoints []SummaryDataPoint `json:"dataPoints"`
+}
+
+// NumberDataPoint is a gauge or sum data point.
+type NumberDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+// HistogramDataPoint is a histogram data point, the bucket counts are not cumulative
+// and there is one bucket more than explicit bounds for the +Inf bucket.
+type HistogramDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
+
+// SummaryDataPoint is a summary data point.
+type SummaryDataPoint struct {
+	Attributes        []KeyValue      `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	QuantileValues    []QuantileValue `json:"quantileValues,omitempty"`
+}
+
+// QuantileValue is a quantile of a summary.
+type QuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+// KeyValue is an attribute.
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is an attribute value, only string values are used.
+type AnyValue struct {
+	StringValue string `json:"stringValue"`
+}
@@ -0,0 +1,197 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+const (
+	exporterSubsystem = "exporter_otlp"
+
+	// ScopeName is the instrumentation scope name of the exported metrics.
+	ScopeName = "github.com/slok/brigade-exporter"
+
+	// Resource attribute keys.
+	ServiceNameKey    = "service.name"
+	ServiceVersionKey = "service.version"
+	ClusterKey        = "k8s.cluster.name"
+	NamespaceKey      = "k8s.namespace.name"
+
+	// Defaults.
+	namespaceDef = "brigade"
+	intervalDef  = 30 * time.Second
+	timeoutDef   = 10 * time.Second
+)
+
+// Config is the Exporter configuration.
+type Config struct {
+	// Endpoint is the OTLP/HTTP metrics endpoint (e.g: http://otel-collector:4318/v1/metrics).
+	Endpoint string
+	// Interval is the interval between the exports.
+	Interval time.Duration
+	// Timeout is the timeout of every request.
+	Timeout time.Duration
+	// Headers are the headers sent on every request (e.g: the authentication headers).
+	Headers map[string]string
+	// ResourceAttributes are the attributes of the resource of the metrics
+	// (e.g: k8s.cluster.name and k8s.namespace.name).
+	ResourceAttributes map[string]string
+	// Version is the version of the instrumentation scope.
+	Version string
+	// Namespace is the namespace of the exporter metrics, by default brigade.
+	Namespace string
+	// Heartbeat is optional and called after every export of the Run loop.
+	Heartbeat func()
+}
+
+// defaults sets the required defaults.
+func (c *Config) defaults() {
+	if c.Namespace == "" {
+		c.Namespace = namespaceDef
+	}
+
+	if c.Interval == 0 {
+		c.Interval = intervalDef
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = timeoutDef
+	}
+
+	if c.Heartbeat == nil {
+		c.Heartbeat = func() {}
+	}
+}
+
+// Exporter exports the metrics of a gatherer periodically to an OTLP/HTTP endpoint using
+// the JSON encoding. Gathering the same registry the Prometheus handler serves makes the
+// collection shared with the scrapes.
+type Exporter struct {
+	exports *prometheus.CounterVec
+
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	start    time.Time
+	cfg      Config
+	logger   log.Logger
+}
+
+// NewExporter returns a new OTLP Exporter.
+func NewExporter(cfg Config, g prometheus.Gatherer, reg prometheus.Registerer, logger log.Logger) (*Exporter, error) {
+	// Fill the required defaults.
+	cfg.defaults()
+
+	e := &Exporter{
+		exports: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: exporterSubsystem,
+			Name:      "exports_total",
+			Help:      "Number of exports made to the OTLP endpoint.",
+		}, []string{"success"}),
+
+		gatherer: g,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		start:    time.Now(),
+		cfg:      cfg,
+		logger:   logger,
+	}
+
+	if err := reg.Register(e.exports); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Export gathers the metrics and exports them.
+func (e *Exporter) Export() error {
+	err := e.export()
+	if err != nil {
+		e.exports.WithLabelValues("false").Inc()
+		return err
+	}
+
+	e.exports.WithLabelValues("true").Inc()
+	return nil
+}
+
+func (e *Exporter) export() error {
+	// The gatherer returns the metrics it could gather even on errors.
+	mfs, gerr := e.gatherer.Gather()
+	if gerr != nil {
+		e.logger.Warningf("error gathering metrics for OTLP export: %s", gerr)
+	}
+
+	req := ExportMetricsServiceRequest{
+		ResourceMetrics: []ResourceMetrics{
+			{
+				Resource: Resource{Attributes: resourceAttributes(e.cfg.ResourceAttributes)},
+				ScopeMetrics: []ScopeMetrics{
+					{
+						Scope:   InstrumentationScope{Name: ScopeName, Version: e.cfg.Version},
+						Metrics: metrics(mfs, e.start.UnixNano(), time.Now().UnixNano()),
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	hreq, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		hreq.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return nil
+}
+
+// Run exports the metrics on every interval until stop is closed, the
+// first export is made when it starts.
+func (e *Exporter) Run(stop <-chan struct{}) {
+	t := time.NewTicker(e.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		if err := e.Export(); err != nil {
+			e.logger.Errorf("error exporting metrics to %s: %s", e.cfg.Endpoint, err)
+		} else {
+			e.logger.Debugf("metrics exported to %s", e.cfg.Endpoint)
+		}
+		e.cfg.Heartbeat()
+
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
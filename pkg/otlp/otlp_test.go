@@ -0,0 +1,297 @@
+package otlp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	brigadecollector "github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/otlp"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+// collector is an OTLP collector stand-in that records the received requests.
+type collector struct {
+	mu       sync.Mutex
+	code     int
+	requests []otlp.ExportMetricsServiceRequest
+	headers  http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := otlp.ExportMetricsServiceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	c.headers = r.Header
+	w.WriteHeader(c.code)
+}
+
+func (c *collector) received() []otlp.ExportMetricsServiceRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]otlp.ExportMetricsServiceRequest{}, c.requests...)
+}
+
+func testRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "brigade_builds_total", Help: "Builds."}, []string{"project_id", "status"})
+	c.WithLabelValues("p1", "").Add(5)
+
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "brigade_up", Help: "Up."})
+	g.Set(1)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "brigade_build_duration_seconds", Help: "Durations.", Buckets: []float64{1, 10}})
+	h.Observe(0.5)
+	h.Observe(5)
+	h.Observe(7)
+	h.Observe(50)
+
+	s := prometheus.NewSummary(prometheus.SummaryOpts{Name: "brigade_job_duration_seconds", Help: "Durations.", Objectives: map[float64]float64{0.5: 0.05}})
+
+	reg.MustRegister(c, g, h, s)
+	return reg
+}
+
+// exporterRegistry returns a registry with the Brigade exporter collecting a mocked Brigade.
+func exporterRegistry() *prometheus.Registry {
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Return([]*brigade.Project{
+		&brigade.Project{ID: "id1", Name: "Name1", Repository: "repo1", Namespace: "ns1", Worker: "worker1"},
+	}, nil)
+	mbsvc.On("GetBuilds").Return([]*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "id1", Type: "push", Provider: "github", Version: "1234567890", Status: "Succeeded"},
+	}, nil)
+
+	cfg := brigadecollector.Config{
+		DisableJobs: true,
+		Metrics:     brigadecollector.MetricsConfig{ConstLabels: map[string]string{"cluster": "prod-1"}},
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(brigadecollector.NewExporter(cfg, mbsvc, log.Dummy))
+	return reg
+}
+
+func TestExporterExport(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int
+		expErr     bool
+		expSuccess string
+	}{
+		{
+			name:       "Exporting should send the metrics mapped to OTLP.",
+			code:       http.StatusOK,
+			expSuccess: "true",
+		},
+		{
+			name:       "A failed export should error.",
+			code:       http.StatusServiceUnavailable,
+			expErr:     true,
+			expSuccess: "false",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			col := &collector{code: test.code}
+			srv := httptest.NewServer(col)
+			defer srv.Close()
+
+			cfg := otlp.Config{
+				Endpoint: srv.URL + "/v1/metrics",
+				Headers:  map[string]string{"Authorization": "Bearer t0k3n"},
+				ResourceAttributes: map[string]string{
+					otlp.ServiceNameKey: "brigade-exporter",
+					otlp.ClusterKey:     "prod-1",
+					otlp.NamespaceKey:   "brigade",
+				},
+				Version: "v1.0.0",
+			}
+			metricsReg := prometheus.NewRegistry()
+			e, err := otlp.NewExporter(cfg, testRegistry(), metricsReg, log.Dummy)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = e.Export()
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			// The export is measured.
+			mfs, _ := metricsReg.Gather()
+			if assert.Len(mfs, 1) {
+				assert.Equal(test.expSuccess, mfs[0].GetMetric()[0].GetLabel()[0].GetValue())
+			}
+
+			reqs := col.received()
+			if !assert.Len(reqs, 1) || !assert.Len(reqs[0].ResourceMetrics, 1) {
+				return
+			}
+			assert.Equal("application/json", col.headers.Get("Content-Type"))
+			assert.Equal("Bearer t0k3n", col.headers.Get("Authorization"))
+
+			rm := reqs[0].ResourceMetrics[0]
+			expAttrs := []otlp.KeyValue{
+				{Key: "k8s.cluster.name", Value: otlp.AnyValue{StringValue: "prod-1"}},
+				{Key: "k8s.namespace.name", Value: otlp.AnyValue{StringValue: "brigade"}},
+				{Key: "service.name", Value: otlp.AnyValue{StringValue: "brigade-exporter"}},
+			}
+			assert.Equal(expAttrs, rm.Resource.Attributes)
+			if !assert.Len(rm.ScopeMetrics, 1) {
+				return
+			}
+			assert.Equal(otlp.InstrumentationScope{Name: otlp.ScopeName, Version: "v1.0.0"}, rm.ScopeMetrics[0].Scope)
+
+			ms := map[string]otlp.Metric{}
+			for _, m := range rm.ScopeMetrics[0].Metrics {
+				ms[m.Name] = m
+			}
+
+			// Counters are monotonic cumulative sums.
+			if sum := ms["brigade_builds_total"].Sum; assert.NotNil(sum) {
+				assert.True(sum.IsMonotonic)
+				assert.Equal(2, sum.AggregationTemporality)
+				if assert.Len(sum.DataPoints, 1) {
+					dp := sum.DataPoints[0]
+					assert.Equal(5.0, dp.AsDouble)
+					assert.Equal([]otlp.KeyValue{{Key: "project_id", Value: otlp.AnyValue{StringValue: "p1"}}}, dp.Attributes)
+					assert.NotEmpty(dp.StartTimeUnixNano)
+					assert.NotEmpty(dp.TimeUnixNano)
+				}
+			}
+
+			// Gauges.
+			if gauge := ms["brigade_up"].Gauge; assert.NotNil(gauge) && assert.Len(gauge.DataPoints, 1) {
+				assert.Equal(1.0, gauge.DataPoints[0].AsDouble)
+				assert.Equal("Up.", ms["brigade_up"].Description)
+			}
+
+			// Histograms have per bucket counts.
+			if h := ms["brigade_build_duration_seconds"].Histogram; assert.NotNil(h) && assert.Len(h.DataPoints, 1) {
+				dp := h.DataPoints[0]
+				assert.Equal("4", dp.Count)
+				assert.Equal(62.5, dp.Sum)
+				assert.Equal([]float64{1, 10}, dp.ExplicitBounds)
+				assert.Equal([]string{"1", "2", "1"}, dp.BucketCounts)
+			}
+
+			// Summaries without observations don't have the NaN quantiles.
+			if s := ms["brigade_job_duration_seconds"].Summary; assert.NotNil(s) && assert.Len(s.DataPoints, 1) {
+				assert.Equal("0", s.DataPoints[0].Count)
+				assert.Empty(s.DataPoints[0].QuantileValues)
+			}
+		})
+	}
+}
+
+func TestExporterRun(t *testing.T) {
+	assert := assert.New(t)
+
+	col := &collector{code: http.StatusOK}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	var beats int32
+	cfg := otlp.Config{
+		Endpoint:  srv.URL,
+		Interval:  10 * time.Millisecond,
+		Heartbeat: func() { atomic.AddInt32(&beats, 1) },
+	}
+	e, err := otlp.NewExporter(cfg, testRegistry(), prometheus.NewRegistry(), log.Dummy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		e.Run(stop)
+		close(done)
+	}()
+
+	// Wait until it has exported multiple times.
+	timeout := time.After(5 * time.Second)
+	for len(col.received()) < 3 {
+		select {
+		case <-timeout:
+			assert.Fail("metrics not exported periodically")
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail("exporter didn't stop")
+	}
+
+	// Every export beats.
+	assert.True(atomic.LoadInt32(&beats) >= 3)
+}
+
+func TestExporterExportBrigadeExporter(t *testing.T) {
+	assert := assert.New(t)
+
+	col := &collector{code: http.StatusOK}
+	srv := httptest.NewServer(col)
+	defer srv.Close()
+
+	e, err := otlp.NewExporter(otlp.Config{Endpoint: srv.URL}, exporterRegistry(), prometheus.NewRegistry(), log.Dummy)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(e.Export())
+
+	reqs := col.received()
+	if !assert.Len(reqs, 1) || !assert.Len(reqs[0].ResourceMetrics, 1) || !assert.Len(reqs[0].ResourceMetrics[0].ScopeMetrics, 1) {
+		return
+	}
+	ms := map[string]otlp.Metric{}
+	for _, m := range reqs[0].ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		ms[m.Name] = m
+	}
+
+	if gauge := ms["brigade_up"].Gauge; assert.NotNil(gauge) && assert.Len(gauge.DataPoints, 1) {
+		assert.Equal(1.0, gauge.DataPoints[0].AsDouble)
+	}
+
+	// The attributes are the metric labels sorted by key.
+	if gauge := ms["brigade_project_info"].Gauge; assert.NotNil(gauge) && assert.Len(gauge.DataPoints, 1) {
+		expAttrs := []otlp.KeyValue{
+			{Key: "cluster", Value: otlp.AnyValue{StringValue: "prod-1"}},
+			{Key: "id", Value: otlp.AnyValue{StringValue: "id1"}},
+			{Key: "name", Value: otlp.AnyValue{StringValue: "Name1"}},
+			{Key: "namespace", Value: otlp.AnyValue{StringValue: "ns1"}},
+			{Key: "repository", Value: otlp.AnyValue{StringValue: "repo1"}},
+			{Key: "worker", Value: otlp.AnyValue{StringValue: "worker1"}},
+		}
+		assert.Equal(expAttrs, gauge.DataPoints[0].Attributes)
+	}
+
+	if gauge := ms["brigade_build_status"].Gauge; assert.NotNil(gauge) && assert.Len(gauge.DataPoints, 1) {
+		assert.Equal(1.0, gauge.DataPoints[0].AsDouble)
+	}
+}
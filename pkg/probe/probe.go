@@ -0,0 +1,142 @@
+package probe
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const targetParam = "target"
+
+// ServiceFactory creates the Brigade service of a target.
+type ServiceFactory func(target string) (brigade.Interface, error)
+
+// Config is the probe handler configuration.
+type Config struct {
+	// Targets are the allowed targets to probe.
+	Targets []string
+	// Exporter is the configuration of the exporters of the targets.
+	Exporter collector.Config
+}
+
+// target is the exporter of a probe target with its Brigade service.
+type target struct {
+	svc      brigade.Interface
+	exporter *collector.Exporter
+	logger   log.Logger
+}
+
+// Handler serves the metrics of the target set with the target query parameter
+// (e.g: /probe?target=brigade), in the style of the blackbox_exporter.
+type Handler struct {
+	cfg     Config
+	allowed map[string]bool
+	factory ServiceFactory
+	logger  log.Logger
+
+	mu      sync.Mutex
+	targets map[string]*target
+}
+
+// NewHandler returns a new probe handler. Only the allowed targets can be probed, the
+// exporter of every target is created on the first probe and reused on the next ones
+// so the Brigade services and the caches are shared between the probes of a target.
+func NewHandler(cfg Config, factory ServiceFactory, logger log.Logger) *Handler {
+	allowed := map[string]bool{}
+	for _, t := range cfg.Targets {
+		allowed[t] = true
+	}
+
+	return &Handler{
+		cfg:     cfg,
+		allowed: allowed,
+		factory: factory,
+		logger:  logger,
+		targets: map[string]*target{},
+	}
+}
+
+// Reload returns a new probe handler with the configuration. The exporters of the
+// targets that are still allowed are kept and reloaded, so they keep their Brigade
+// services, caches and state, the exporters of the removed targets are dropped.
+func (h *Handler) Reload(cfg Config) *Handler {
+	rh := NewHandler(cfg, h.factory, h.logger)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for name, t := range h.targets {
+		if !rh.allowed[name] {
+			t.logger.Infof("target exporter removed")
+			continue
+		}
+		rh.targets[name] = &target{
+			svc:      t.svc,
+			exporter: t.exporter.Reload(cfg.Exporter, t.svc, t.logger),
+			logger:   t.logger,
+		}
+	}
+
+	return rh
+}
+
+// ServeHTTP satisfies http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get(targetParam)
+	if target == "" {
+		http.Error(w, fmt.Sprintf("%s parameter is missing", targetParam), http.StatusBadRequest)
+		return
+	}
+
+	if !h.allowed[target] {
+		h.logger.Warnf("probe of not allowed %s target", target)
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		return
+	}
+
+	exporter, err := h.exporter(target)
+	if err != nil {
+		h.logger.Errorf("error creating %s target exporter: %s", target, err)
+		http.Error(w, fmt.Sprintf("error creating target exporter: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(exporter); err != nil {
+		http.Error(w, fmt.Sprintf("couldn't register target exporter: %s", err), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// exporter returns the exporter of the target creating it if required.
+func (h *Handler) exporter(name string) (*collector.Exporter, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.targets[name]; ok {
+		return t.exporter, nil
+	}
+
+	svc, err := h.factory(name)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := h.logger.With("target", name)
+	t := &target{
+		svc:      svc,
+		exporter: collector.NewExporter(h.cfg.Exporter, svc, logger),
+		logger:   logger,
+	}
+	h.targets[name] = t
+	logger.Infof("target exporter created")
+
+	return t.exporter, nil
+}
@@ -0,0 +1,174 @@
+package probe_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/probe"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		factoryErr    error
+		expCode       int
+		expMetrics    []string
+		notExpMetrics []string
+	}{
+		{
+			name:    "Probing without target should fail.",
+			url:     "/probe",
+			expCode: http.StatusBadRequest,
+		},
+		{
+			name:    "Probing a not allowed target should be forbidden.",
+			url:     "/probe?target=kube-system",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:       "Probing a target that can't be created should fail.",
+			url:        "/probe?target=team1",
+			factoryErr: fmt.Errorf("wanted error"),
+			expCode:    http.StatusInternalServerError,
+		},
+		{
+			name:    "Probing an allowed target should return the metrics of the target.",
+			url:     "/probe?target=team1",
+			expCode: http.StatusOK,
+			expMetrics: []string{
+				`brigade_up 1`,
+				`brigade_project_info{id="team1-prj",name="team1",namespace="team1",repository="",worker=""} 1`,
+			},
+			notExpMetrics: []string{
+				`name="team2"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			factory := func(target string) (brigade.Interface, error) {
+				if test.factoryErr != nil {
+					return nil, test.factoryErr
+				}
+				mbsvc := &mbrigade.Interface{}
+				mbsvc.On("GetProjects").Return([]*brigade.Project{
+					&brigade.Project{ID: target + "-prj", Name: target, Namespace: target},
+				}, nil)
+				mbsvc.On("GetBuilds").Return([]*brigade.Build{}, nil)
+				return mbsvc, nil
+			}
+
+			cfg := probe.Config{
+				Targets:  []string{"team1", "team2"},
+				Exporter: collector.Config{DisableBuilds: true, DisableJobs: true},
+			}
+			h := probe.NewHandler(cfg, factory, log.Dummy)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.url, nil)
+			h.ServeHTTP(rec, req)
+			resp := rec.Result()
+
+			if assert.Equal(test.expCode, resp.StatusCode) {
+				body, _ := ioutil.ReadAll(resp.Body)
+				for _, expMetric := range test.expMetrics {
+					assert.Contains(string(body), expMetric)
+				}
+				for _, notExpMetric := range test.notExpMetrics {
+					assert.NotContains(string(body), notExpMetric)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeReusesTargetExporters(t *testing.T) {
+	assert := assert.New(t)
+
+	created := map[string]int{}
+	factory := func(target string) (brigade.Interface, error) {
+		created[target]++
+		mbsvc := &mbrigade.Interface{}
+		mbsvc.On("GetProjects").Return([]*brigade.Project{}, nil)
+		mbsvc.On("GetBuilds").Return([]*brigade.Build{}, nil)
+		return mbsvc, nil
+	}
+
+	cfg := probe.Config{
+		Targets:  []string{"team1", "team2"},
+		Exporter: collector.Config{DisableBuilds: true, DisableJobs: true},
+	}
+	h := probe.NewHandler(cfg, factory, log.Dummy)
+
+	for _, target := range []string{"team1", "team2", "team1", "team1"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/probe?target="+target, nil)
+		h.ServeHTTP(rec, req)
+		assert.Equal(http.StatusOK, rec.Code)
+	}
+
+	assert.Equal(map[string]int{"team1": 1, "team2": 1}, created)
+}
+
+func TestProbeReload(t *testing.T) {
+	assert := assert.New(t)
+
+	created := map[string]int{}
+	factory := func(target string) (brigade.Interface, error) {
+		created[target]++
+		mbsvc := &mbrigade.Interface{}
+		mbsvc.On("GetProjects").Return([]*brigade.Project{
+			&brigade.Project{ID: target + "-prj", Name: target, Namespace: target},
+		}, nil)
+		mbsvc.On("GetBuilds").Return([]*brigade.Build{}, nil)
+		return mbsvc, nil
+	}
+	probeTarget := func(h http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/probe?target="+target, nil)
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cfg := probe.Config{
+		Targets:  []string{"team1", "team2"},
+		Exporter: collector.Config{DisableBuilds: true, DisableJobs: true},
+	}
+	h := probe.NewHandler(cfg, factory, log.Dummy)
+	assert.Equal(http.StatusOK, probeTarget(h, "team1").Code)
+	assert.Equal(http.StatusOK, probeTarget(h, "team2").Code)
+
+	// Reload with a removed target, a new target and a new exporter configuration.
+	cfg = probe.Config{
+		Targets: []string{"team1", "team3"},
+		Exporter: collector.Config{
+			DisableBuilds: true,
+			DisableJobs:   true,
+			Metrics:       collector.MetricsConfig{ConstLabels: map[string]string{"cluster": "prod-1"}},
+		},
+	}
+	rh := h.Reload(cfg)
+
+	// The kept targets should use the new configuration without being created again.
+	rec := probeTarget(rh, "team1")
+	if assert.Equal(http.StatusOK, rec.Code) {
+		assert.Contains(rec.Body.String(), `brigade_project_info{cluster="prod-1",id="team1-prj"`)
+	}
+	assert.Equal(http.StatusForbidden, probeTarget(rh, "team2").Code)
+	assert.Equal(http.StatusOK, probeTarget(rh, "team3").Code)
+
+	assert.Equal(map[string]int{"team1": 1, "team2": 1, "team3": 1}, created)
+}
@@ -0,0 +1,144 @@
+package pushgateway
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+const (
+	pusherSubsystem = "exporter_pushgateway"
+
+	// Defaults.
+	namespaceDef = "brigade"
+	jobDef       = "brigade-exporter"
+	intervalDef  = 30 * time.Second
+)
+
+// Config is the Pusher configuration.
+type Config struct {
+	// URL is the URL of the Pushgateway.
+	URL string
+	// Job is the job of the pushed metrics, by default brigade-exporter.
+	Job string
+	// Grouping are the labels of the grouping key, apart from the job.
+	Grouping map[string]string
+	// Interval is the interval between the pushes.
+	Interval time.Duration
+	// Username and Password are the optional basic auth credentials.
+	Username string
+	Password string
+	// Namespace is the namespace of the pusher metrics, by default brigade.
+	Namespace string
+	// Heartbeat is optional and called after every push of the Run loop.
+	Heartbeat func()
+}
+
+// defaults sets the required defaults.
+func (c *Config) defaults() {
+	if c.Namespace == "" {
+		c.Namespace = namespaceDef
+	}
+
+	if c.Job == "" {
+		c.Job = jobDef
+	}
+
+	if c.Interval == 0 {
+		c.Interval = intervalDef
+	}
+
+	if c.Heartbeat == nil {
+		c.Heartbeat = func() {}
+	}
+}
+
+// Pusher pushes the metrics of a gatherer to a Pushgateway periodically.
+type Pusher struct {
+	pushes *prometheus.CounterVec
+	pusher *push.Pusher
+	cfg    Config
+	logger log.Logger
+}
+
+// NewPusher returns a new Pusher that pushes the metrics of the gatherer replacing
+// the previously pushed metrics of the same grouping key.
+func NewPusher(cfg Config, g prometheus.Gatherer, reg prometheus.Registerer, logger log.Logger) (*Pusher, error) {
+	// Fill the required defaults.
+	cfg.defaults()
+
+	pusher := push.New(cfg.URL, cfg.Job).
+		Gatherer(g).
+		Client(&http.Client{Timeout: cfg.Interval})
+
+	// Sort the grouping labels so the grouping key path is always the same.
+	names := make([]string, 0, len(cfg.Grouping))
+	for name := range cfg.Grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pusher = pusher.Grouping(name, cfg.Grouping[name])
+	}
+
+	if cfg.Username != "" {
+		pusher = pusher.BasicAuth(cfg.Username, cfg.Password)
+	}
+
+	p := &Pusher{
+		pushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: pusherSubsystem,
+			Name:      "pushes_total",
+			Help:      "Number of pushes made to the Pushgateway.",
+		}, []string{"success"}),
+		pusher: pusher,
+		cfg:    cfg,
+		logger: logger,
+	}
+
+	if err := reg.Register(p.pushes); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Push gathers the metrics and pushes them.
+func (p *Pusher) Push() error {
+	err := p.pusher.Push()
+	if err != nil {
+		p.pushes.WithLabelValues("false").Inc()
+		return err
+	}
+
+	p.pushes.WithLabelValues("true").Inc()
+	return nil
+}
+
+// Run pushes the metrics on every interval until stop is closed, the
+// first push is made when it starts.
+func (p *Pusher) Run(stop <-chan struct{}) {
+	t := time.NewTicker(p.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		if err := p.Push(); err != nil {
+			p.logger.Errorf("error pushing metrics to %s: %s", p.cfg.URL, err)
+		} else {
+			p.logger.Debugf("metrics pushed to %s", p.cfg.URL)
+		}
+		p.cfg.Heartbeat()
+
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package pushgateway_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/pushgateway"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+// pushRequest is a request received by the Pushgateway.
+type pushRequest struct {
+	method   string
+	path     string
+	user     string
+	password string
+	families map[string]*dto.MetricFamily
+}
+
+// pushgatewayServer is a Pushgateway stand-in that records the pushes.
+type pushgatewayServer struct {
+	mu       sync.Mutex
+	code     int
+	requests []pushRequest
+}
+
+func (s *pushgatewayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := pushRequest{
+		method:   r.Method,
+		path:     r.URL.Path,
+		families: map[string]*dto.MetricFamily{},
+	}
+	req.user, req.password, _ = r.BasicAuth()
+
+	dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			break
+		}
+		req.families[mf.GetName()] = mf
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	w.WriteHeader(s.code)
+}
+
+func (s *pushgatewayServer) pushes() []pushRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pushRequest{}, s.requests...)
+}
+
+func testRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "brigade_up", Help: "Whether Brigade is reachable."})
+	g.Set(1)
+	reg.MustRegister(g)
+	return reg
+}
+
+// exporterRegistry returns a registry with the Brigade exporter collecting a mocked Brigade.
+func exporterRegistry() *prometheus.Registry {
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Return([]*brigade.Project{
+		&brigade.Project{ID: "id1", Name: "Name1", Repository: "repo1", Namespace: "ns1", Worker: "worker1"},
+	}, nil)
+	mbsvc.On("GetBuilds").Return([]*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "id1", Type: "push", Provider: "github", Version: "1234567890", Status: "Succeeded"},
+	}, nil)
+
+	cfg := collector.Config{
+		DisableJobs: true,
+		Metrics:     collector.MetricsConfig{ConstLabels: map[string]string{"cluster": "prod-1"}},
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector.NewExporter(cfg, mbsvc, log.Dummy))
+	return reg
+}
+
+func TestPusherPush(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         pushgateway.Config
+		code        int
+		expErr      bool
+		expPath     string
+		expUser     string
+		expPassword string
+		expPushes   string
+	}{
+		{
+			name:      "Pushing with the default configuration should push the metrics with the default job.",
+			code:      http.StatusAccepted,
+			expPath:   "/metrics/job/brigade-exporter",
+			expPushes: "true",
+		},
+		{
+			name: "Pushing with a custom job, grouping key and basic auth should push with them.",
+			cfg: pushgateway.Config{
+				Job:      "brigade",
+				Grouping: map[string]string{"cluster": "prod-1", "az": "eu-west-1a"},
+				Username: "pusher",
+				Password: "s3cr3t",
+			},
+			code:        http.StatusAccepted,
+			expPath:     "/metrics/job/brigade/az/eu-west-1a/cluster/prod-1",
+			expUser:     "pusher",
+			expPassword: "s3cr3t",
+			expPushes:   "true",
+		},
+		{
+			name:      "A failed push should error.",
+			code:      http.StatusInternalServerError,
+			expErr:    true,
+			expPath:   "/metrics/job/brigade-exporter",
+			expPushes: "false",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			pgw := &pushgatewayServer{code: test.code}
+			srv := httptest.NewServer(pgw)
+			defer srv.Close()
+
+			reg := testRegistry()
+			cfg := test.cfg
+			cfg.URL = srv.URL
+			p, err := pushgateway.NewPusher(cfg, reg, reg, log.Dummy)
+			if !assert.NoError(err) {
+				return
+			}
+
+			err = p.Push()
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			pushes := pgw.pushes()
+			if assert.Len(pushes, 1) {
+				push := pushes[0]
+				assert.Equal("PUT", push.method)
+				assert.Equal(test.expPath, push.path)
+				assert.Equal(test.expUser, push.user)
+				assert.Equal(test.expPassword, push.password)
+				if assert.Contains(push.families, "brigade_up") {
+					assert.Equal(1.0, push.families["brigade_up"].GetMetric()[0].GetGauge().GetValue())
+				}
+			}
+
+			// The pushes are measured on the pushed registry.
+			mfs, _ := reg.Gather()
+			for _, mf := range mfs {
+				if mf.GetName() == "brigade_exporter_pushgateway_pushes_total" {
+					assert.Equal(test.expPushes, mf.GetMetric()[0].GetLabel()[0].GetValue())
+				}
+			}
+		})
+	}
+}
+
+func TestPusherPushBrigadeExporter(t *testing.T) {
+	assert := assert.New(t)
+
+	pgw := &pushgatewayServer{code: http.StatusAccepted}
+	srv := httptest.NewServer(pgw)
+	defer srv.Close()
+
+	p, err := pushgateway.NewPusher(pushgateway.Config{URL: srv.URL}, exporterRegistry(), prometheus.NewRegistry(), log.Dummy)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(p.Push())
+
+	pushes := pgw.pushes()
+	if !assert.Len(pushes, 1) {
+		return
+	}
+	families := pushes[0].families
+	if assert.Contains(families, "brigade_up") {
+		assert.Equal(1.0, families["brigade_up"].GetMetric()[0].GetGauge().GetValue())
+	}
+	if assert.Contains(families, "brigade_project_info") {
+		labels := map[string]string{}
+		for _, l := range families["brigade_project_info"].GetMetric()[0].GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(map[string]string{"cluster": "prod-1", "id": "id1", "name": "Name1", "namespace": "ns1", "repository": "repo1", "worker": "worker1"}, labels)
+	}
+	assert.Contains(families, "brigade_exporter_collector_success")
+}
+
+func TestPusherRun(t *testing.T) {
+	assert := assert.New(t)
+
+	pgw := &pushgatewayServer{code: http.StatusAccepted}
+	srv := httptest.NewServer(pgw)
+	defer srv.Close()
+
+	var beats int32
+	cfg := pushgateway.Config{
+		URL:       srv.URL,
+		Interval:  10 * time.Millisecond,
+		Heartbeat: func() { atomic.AddInt32(&beats, 1) },
+	}
+	reg := testRegistry()
+	p, err := pushgateway.NewPusher(cfg, reg, reg, log.Dummy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		p.Run(stop)
+		close(done)
+	}()
+
+	// Wait until it has pushed multiple times.
+	timeout := time.After(5 * time.Second)
+	for len(pgw.pushes()) < 3 {
+		select {
+		case <-timeout:
+			assert.Fail("metrics not pushed periodically")
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail("pusher didn't stop")
+	}
+
+	// Every push beats.
+	assert.True(atomic.LoadInt32(&beats) >= 3)
+}
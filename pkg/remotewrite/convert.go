@@ -0,0 +1,113 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	nameLabel     = "__name__"
+	bucketLabel   = "le"
+	quantileLabel = "quantile"
+)
+
+// timeSeries returns the series of the metric families with the same names and labels
+// Prometheus would get scraping them, the summaries and histograms are split in multiple
+// series. The external labels are added to the series that don't have them, the samples
+// without timestamp get the timestamp (in milliseconds).
+func timeSeries(mfs []*dto.MetricFamily, externalLabels map[string]string, timestamp int64) []*TimeSeries {
+	var res []*TimeSeries
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+
+			add := func(name string, value float64, extra ...*Label) {
+				res = append(res, &TimeSeries{
+					Labels:  seriesLabels(name, m.GetLabel(), externalLabels, extra...),
+					Samples: []*Sample{{Value: value, Timestamp: ts}},
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), &Label{Name: quantileLabel, Value: formatFloat(q.GetQuantile())})
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				infSeen := false
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), +1) {
+						infSeen = true
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), &Label{Name: bucketLabel, Value: formatFloat(b.GetUpperBound())})
+				}
+				if !infSeen {
+					add(name+"_bucket", float64(h.GetSampleCount()), &Label{Name: bucketLabel, Value: "+Inf"})
+				}
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+
+	return res
+}
+
+// seriesLabels returns the labels of a series sorted by name, the labels with an empty
+// value are dropped like Prometheus does.
+func seriesLabels(name string, metricLabels []*dto.LabelPair, externalLabels map[string]string, extra ...*Label) []*Label {
+	labels := make([]*Label, 0, len(metricLabels)+len(externalLabels)+len(extra)+1)
+	seen := map[string]bool{}
+
+	labels = append(labels, &Label{Name: nameLabel, Value: name})
+	for _, l := range metricLabels {
+		if l.GetValue() == "" {
+			continue
+		}
+		labels = append(labels, &Label{Name: l.GetName(), Value: l.GetValue()})
+		seen[l.GetName()] = true
+	}
+	for _, l := range extra {
+		labels = append(labels, l)
+		seen[l.Name] = true
+	}
+	for k, v := range externalLabels {
+		if !seen[k] {
+			labels = append(labels, &Label{Name: k, Value: v})
+		}
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+// formatFloat formats the bucket and quantile label values like Prometheus.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,67 @@
+package remotewrite
+
+import (
+	"github.com/golang/protobuf/proto"
+)
+
+// The Prometheus remote write protobuf messages (prompb), only the fields
+// required to send the samples.
+
+// WriteRequest is the remote write request.
+type WriteRequest struct {
+	Timeseries []*TimeSeries `protobuf:"bytes,1,rep,name=timeseries" json:"timeseries,omitempty"`
+}
+
+// Reset satisfies proto.Message interface.
+func (m *WriteRequest) Reset() { *m = WriteRequest{} }
+
+// String satisfies proto.Message interface.
+func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage satisfies proto.Message interface.
+func (*WriteRequest) ProtoMessage() {}
+
+// TimeSeries is a series with its samples, the labels must be sorted by name.
+type TimeSeries struct {
+	Labels  []*Label  `protobuf:"bytes,1,rep,name=labels" json:"labels,omitempty"`
+	Samples []*Sample `protobuf:"bytes,2,rep,name=samples" json:"samples,omitempty"`
+}
+
+// Reset satisfies proto.Message interface.
+func (m *TimeSeries) Reset() { *m = TimeSeries{} }
+
+// String satisfies proto.Message interface.
+func (m *TimeSeries) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage satisfies proto.Message interface.
+func (*TimeSeries) ProtoMessage() {}
+
+// Label is a label of a series.
+type Label struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+// Reset satisfies proto.Message interface.
+func (m *Label) Reset() { *m = Label{} }
+
+// String satisfies proto.Message interface.
+func (m *Label) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage satisfies proto.Message interface.
+func (*Label) ProtoMessage() {}
+
+// Sample is a sample of a series, the timestamp is in milliseconds.
+type Sample struct {
+	Value     float64 `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	Timestamp int64   `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+}
+
+// Reset satisfies proto.Message interface.
+func (m *Sample) Reset() { *m = Sample{} }
+
+// String satisfies proto.Message interface.
+func (m *Sample) String() string { return proto.CompactTextString(m) }
+
+// ProtoMessage satisfies proto.Message interface.
+func (*Sample) ProtoMessage() {}
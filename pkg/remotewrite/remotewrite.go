@@ -0,0 +1,321 @@
+package remotewrite
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+const (
+	writerSubsystem = "exporter_remote_write"
+
+	userAgent = "brigade-exporter"
+
+	// Defaults.
+	namespaceDef  = "brigade"
+	intervalDef   = 30 * time.Second
+	timeoutDef    = 10 * time.Second
+	batchSizeDef  = 500
+	queueSizeDef  = 100
+	maxRetriesDef = 3
+	minBackoffDef = 100 * time.Millisecond
+	maxBackoffDef = 5 * time.Second
+)
+
+// Config is the Writer configuration.
+type Config struct {
+	// URL is the remote write endpoint.
+	URL string
+	// Interval is the interval between the gatherings.
+	Interval time.Duration
+	// Timeout is the timeout of every request.
+	Timeout time.Duration
+	// BatchSize is the max number of samples sent on every request.
+	BatchSize int
+	// QueueSize is the max number of batches waiting to be sent, the new
+	// batches are dropped when the queue is full.
+	QueueSize int
+	// MaxRetries is the max number of retries of a failed request, the requests
+	// are only retried on network errors, 5xx and 429 responses. Negative
+	// values disable the retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff are the wait limits between retries, the wait is
+	// doubled on every retry.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ExternalLabels are the labels added to all the series.
+	ExternalLabels map[string]string
+	// Username and Password are the optional basic auth credentials.
+	Username string
+	Password string
+	// Namespace is the namespace of the writer metrics, by default brigade.
+	Namespace string
+	// Heartbeat is optional and called after every gathering of the Run loop.
+	Heartbeat func()
+	// SenderHeartbeat is optional and called after every sent batch, and on every
+	// interval while there is nothing to send.
+	SenderHeartbeat func()
+}
+
+// defaults sets the required defaults.
+func (c *Config) defaults() {
+	if c.Namespace == "" {
+		c.Namespace = namespaceDef
+	}
+
+	if c.Interval == 0 {
+		c.Interval = intervalDef
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = timeoutDef
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = batchSizeDef
+	}
+
+	if c.QueueSize <= 0 {
+		c.QueueSize = queueSizeDef
+	}
+
+	switch {
+	case c.MaxRetries == 0:
+		c.MaxRetries = maxRetriesDef
+	case c.MaxRetries < 0:
+		c.MaxRetries = 0
+	}
+
+	if c.MinBackoff == 0 {
+		c.MinBackoff = minBackoffDef
+	}
+
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = maxBackoffDef
+		if c.MaxBackoff < c.MinBackoff {
+			c.MaxBackoff = c.MinBackoff
+		}
+	}
+
+	if c.Heartbeat == nil {
+		c.Heartbeat = func() {}
+	}
+
+	if c.SenderHeartbeat == nil {
+		c.SenderHeartbeat = func() {}
+	}
+}
+
+// recoverableError is an error of a request that can be retried.
+type recoverableError struct {
+	error
+}
+
+// Writer is a Prometheus remote write client that gathers the metrics of a gatherer
+// periodically and sends them batched using a queue.
+type Writer struct {
+	samples *prometheus.CounterVec
+	retries prometheus.Counter
+
+	gatherer prometheus.Gatherer
+	queue    chan []*TimeSeries
+	client   *http.Client
+	cfg      Config
+	logger   log.Logger
+}
+
+// NewWriter returns a new remote write Writer.
+func NewWriter(cfg Config, g prometheus.Gatherer, reg prometheus.Registerer, logger log.Logger) (*Writer, error) {
+	// Fill the required defaults.
+	cfg.defaults()
+
+	w := &Writer{
+		samples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: writerSubsystem,
+			Name:      "samples_total",
+			Help:      "Number of samples processed by the remote write by result (sent, failed or dropped).",
+		}, []string{"result"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: writerSubsystem,
+			Name:      "retries_total",
+			Help:      "Number of retried remote write requests.",
+		}),
+
+		gatherer: g,
+		queue:    make(chan []*TimeSeries, cfg.QueueSize),
+		client:   &http.Client{Timeout: cfg.Timeout},
+		cfg:      cfg,
+		logger:   logger,
+	}
+
+	queued := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: cfg.Namespace,
+		Subsystem: writerSubsystem,
+		Name:      "queue_batches",
+		Help:      "Number of batches waiting to be sent.",
+	}, func() float64 { return float64(len(w.queue)) })
+
+	for _, c := range []prometheus.Collector{w.samples, w.retries, queued} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// Gather gathers the metrics and queues them in batches to be sent.
+func (w *Writer) Gather() error {
+	// The gatherer returns the metrics it could gather even on errors.
+	mfs, gerr := w.gatherer.Gather()
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	series := timeSeries(mfs, w.cfg.ExternalLabels, now)
+
+	// Every series has a single sample.
+	for len(series) > 0 {
+		n := w.cfg.BatchSize
+		if n > len(series) {
+			n = len(series)
+		}
+		batch := series[:n]
+		series = series[n:]
+
+		select {
+		case w.queue <- batch:
+		default:
+			w.samples.WithLabelValues("dropped").Add(float64(len(batch)))
+			w.logger.Warningf("remote write queue is full, dropping %d samples", len(batch))
+		}
+	}
+
+	return gerr
+}
+
+// Run gathers the metrics on every interval and sends the queued batches until stop
+// is closed, the first gathering is made when it starts.
+func (w *Writer) Run(stop <-chan struct{}) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.sendQueued(stop)
+	}()
+
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		if err := w.Gather(); err != nil {
+			w.logger.Errorf("error gathering metrics for remote write: %s", err)
+		}
+		w.cfg.Heartbeat()
+
+		select {
+		case <-stop:
+			<-done
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// sendQueued sends the queued batches one after another.
+func (w *Writer) sendQueued(stop <-chan struct{}) {
+	// Beat while idle so an empty queue is not a stalled sender.
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			w.cfg.SenderHeartbeat()
+		case batch := <-w.queue:
+			if err := w.sendBatch(batch, stop); err != nil {
+				w.samples.WithLabelValues("failed").Add(float64(len(batch)))
+				w.logger.Errorf("error sending %d samples to %s: %s", len(batch), w.cfg.URL, err)
+			} else {
+				w.samples.WithLabelValues("sent").Add(float64(len(batch)))
+			}
+			w.cfg.SenderHeartbeat()
+		}
+	}
+}
+
+// sendBatch sends a batch retrying the recoverable errors.
+func (w *Writer) sendBatch(batch []*TimeSeries, stop <-chan struct{}) error {
+	data, err := proto.Marshal(&WriteRequest{Timeseries: batch})
+	if err != nil {
+		return err
+	}
+	body := snappy.Encode(nil, data)
+
+	backoff := w.cfg.MinBackoff
+	for try := 0; ; try++ {
+		err := w.write(body)
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := err.(recoverableError); !ok || try >= w.cfg.MaxRetries {
+			return err
+		}
+
+		w.retries.Inc()
+		w.logger.Debugf("retrying remote write in %s: %s", backoff, err)
+		select {
+		case <-stop:
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+// write makes a remote write request.
+func (w *Writer) write(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.cfg.Username != "" {
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("server returned HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
@@ -0,0 +1,342 @@
+package remotewrite_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/remotewrite"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+// receiver is a remote write receiver stand-in that records the received
+// series, it answers with the codes in order and then with 204.
+type receiver struct {
+	mu       sync.Mutex
+	codes    []int
+	requests int
+	series   map[string]float64
+	headers  http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if len(r.codes) > 0 {
+		code := r.codes[0]
+		r.codes = r.codes[1:]
+		w.WriteHeader(code)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	data, err := snappy.Decode(nil, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wr := &remotewrite.WriteRequest{}
+	if err := proto.Unmarshal(data, wr); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.headers = req.Header
+	if r.series == nil {
+		r.series = map[string]float64{}
+	}
+	for _, ts := range wr.Timeseries {
+		r.series[seriesID(ts)] = ts.Samples[0].Value
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) received() (int, map[string]float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, r.series
+}
+
+// seriesID returns the series as a string with the labels in the received order
+// (e.g: `__name__="up",job="brigade"`), so the expected IDs check the labels are sorted.
+func seriesID(ts *remotewrite.TimeSeries) string {
+	ls := make([]string, len(ts.Labels))
+	for i, l := range ts.Labels {
+		ls[i] = l.Name + `="` + l.Value + `"`
+	}
+	return strings.Join(ls, ",")
+}
+
+func testRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "brigade_builds_total", Help: "Builds."}, []string{"project_id", "cluster"})
+	c.WithLabelValues("p1", "").Add(5)
+	c.WithLabelValues("p2", "local").Add(3)
+
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "brigade_up", Help: "Up."})
+	g.Set(1)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "brigade_build_duration_seconds", Help: "Durations.", Buckets: []float64{1, 10}})
+	h.Observe(0.5)
+	h.Observe(5)
+	h.Observe(50)
+
+	s := prometheus.NewSummary(prometheus.SummaryOpts{Name: "brigade_job_duration_seconds", Help: "Durations.", Objectives: map[float64]float64{0.5: 0.05}})
+	s.Observe(2)
+
+	reg.MustRegister(c, g, h, s)
+	return reg
+}
+
+// exporterRegistry returns a registry with the Brigade exporter collecting a mocked Brigade.
+func exporterRegistry() *prometheus.Registry {
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Return([]*brigade.Project{
+		&brigade.Project{ID: "id1", Name: "Name1", Repository: "repo1", Namespace: "ns1", Worker: "worker1"},
+	}, nil)
+	mbsvc.On("GetBuilds").Return([]*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "id1", Type: "push", Provider: "github", Version: "1234567890", Status: "Succeeded"},
+	}, nil)
+
+	cfg := collector.Config{
+		DisableJobs: true,
+		Metrics:     collector.MetricsConfig{ConstLabels: map[string]string{"cluster": "prod-1"}},
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector.NewExporter(cfg, mbsvc, log.Dummy))
+	return reg
+}
+
+// waitFor waits until the condition is met.
+func waitFor(t *testing.T, cond func() bool) bool {
+	timeout := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case <-timeout:
+			return false
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         remotewrite.Config
+		codes       []int
+		expRequests int
+		expSeries   map[string]float64
+		expMetrics  map[string]float64
+	}{
+		{
+			name: "Writing should send the series batched with the external labels.",
+			cfg: remotewrite.Config{
+				BatchSize:      4,
+				ExternalLabels: map[string]string{"cluster": "prod-1"},
+			},
+			expRequests: 3,
+			expSeries: map[string]float64{
+				`__name__="brigade_build_duration_seconds_bucket",cluster="prod-1",le="1"`:    1,
+				`__name__="brigade_build_duration_seconds_bucket",cluster="prod-1",le="10"`:   2,
+				`__name__="brigade_build_duration_seconds_bucket",cluster="prod-1",le="+Inf"`: 3,
+				`__name__="brigade_build_duration_seconds_count",cluster="prod-1"`:            3,
+				`__name__="brigade_build_duration_seconds_sum",cluster="prod-1"`:              55.5,
+				`__name__="brigade_builds_total",cluster="prod-1",project_id="p1"`:            5,
+				`__name__="brigade_builds_total",cluster="local",project_id="p2"`:             3,
+				`__name__="brigade_job_duration_seconds",cluster="prod-1",quantile="0.5"`:     2,
+				`__name__="brigade_job_duration_seconds_count",cluster="prod-1"`:              1,
+				`__name__="brigade_job_duration_seconds_sum",cluster="prod-1"`:                2,
+				`__name__="brigade_up",cluster="prod-1"`:                                      1,
+			},
+			expMetrics: map[string]float64{
+				"sent":    11,
+				"retries": 0,
+			},
+		},
+		{
+			name:        "Recoverable errors should be retried.",
+			cfg:         remotewrite.Config{MinBackoff: time.Millisecond},
+			codes:       []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			expRequests: 3,
+			expMetrics: map[string]float64{
+				"sent":    11,
+				"retries": 2,
+			},
+		},
+		{
+			name:        "Recoverable errors should fail after the max retries.",
+			cfg:         remotewrite.Config{MinBackoff: time.Millisecond, MaxRetries: 1},
+			codes:       []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			expRequests: 2,
+			expMetrics: map[string]float64{
+				"failed":  11,
+				"retries": 1,
+			},
+		},
+		{
+			name:        "Unrecoverable errors shouldn't be retried.",
+			cfg:         remotewrite.Config{MinBackoff: time.Millisecond},
+			codes:       []int{http.StatusBadRequest},
+			expRequests: 1,
+			expMetrics: map[string]float64{
+				"failed":  11,
+				"retries": 0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			rcv := &receiver{codes: test.codes}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			cfg := test.cfg
+			cfg.URL = srv.URL
+			cfg.Interval = time.Hour
+			// Use a different registry for the writer metrics so they are not sent.
+			metricsReg := prometheus.NewRegistry()
+			w, err := remotewrite.NewWriter(cfg, testRegistry(), metricsReg, log.Dummy)
+			if !assert.NoError(err) {
+				return
+			}
+
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				w.Run(stop)
+				close(done)
+			}()
+
+			ok := waitFor(t, func() bool {
+				m := writerMetrics(metricsReg)
+				return m["sent"]+m["failed"] == 11
+			})
+			close(stop)
+			<-done
+			if !assert.True(ok, "samples not processed") {
+				return
+			}
+
+			requests, series := rcv.received()
+			assert.Equal(test.expRequests, requests)
+			if test.expSeries != nil {
+				assert.Equal(test.expSeries, series)
+				assert.Equal("snappy", rcv.headers.Get("Content-Encoding"))
+				assert.Equal("application/x-protobuf", rcv.headers.Get("Content-Type"))
+				assert.Equal("0.1.0", rcv.headers.Get("X-Prometheus-Remote-Write-Version"))
+			}
+
+			m := writerMetrics(metricsReg)
+			for k, v := range test.expMetrics {
+				assert.Equal(v, m[k], k)
+			}
+		})
+	}
+}
+
+func TestWriterBrigadeExporter(t *testing.T) {
+	assert := assert.New(t)
+
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	var beats, senderBeats int32
+	metricsReg := prometheus.NewRegistry()
+	cfg := remotewrite.Config{
+		URL:             srv.URL,
+		Interval:        time.Hour,
+		ExternalLabels:  map[string]string{"cluster": "edge-1", "region": "eu"},
+		Heartbeat:       func() { atomic.AddInt32(&beats, 1) },
+		SenderHeartbeat: func() { atomic.AddInt32(&senderBeats, 1) },
+	}
+	w, err := remotewrite.NewWriter(cfg, exporterRegistry(), metricsReg, log.Dummy)
+	if !assert.NoError(err) {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.Run(stop)
+		close(done)
+	}()
+
+	ok := waitFor(t, func() bool {
+		_, series := rcv.received()
+		return len(series) > 0 && writerMetrics(metricsReg)["sent"] == float64(len(series))
+	})
+	close(stop)
+	<-done
+	if !assert.True(ok, "samples not sent") {
+		return
+	}
+
+	// The gathering and the sent batch beat.
+	assert.Equal(int32(1), atomic.LoadInt32(&beats))
+	assert.Equal(int32(1), atomic.LoadInt32(&senderBeats))
+
+	// The exporter labels are kept over the external labels.
+	_, series := rcv.received()
+	assert.Equal(1.0, series[`__name__="brigade_up",cluster="prod-1",region="eu"`])
+	assert.Equal(1.0, series[`__name__="brigade_project_info",cluster="prod-1",id="id1",name="Name1",namespace="ns1",region="eu",repository="repo1",worker="worker1"`])
+}
+
+func TestWriterQueueFull(t *testing.T) {
+	assert := assert.New(t)
+
+	metricsReg := prometheus.NewRegistry()
+	w, err := remotewrite.NewWriter(remotewrite.Config{URL: "http://127.0.0.1:0", BatchSize: 5, QueueSize: 2}, testRegistry(), metricsReg, log.Dummy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is sending so only the first 2 batches (10 samples) are queued.
+	assert.NoError(w.Gather())
+	m := writerMetrics(metricsReg)
+	assert.Equal(1.0, m["dropped"])
+	assert.Equal(2.0, m["queue"])
+
+	assert.NoError(w.Gather())
+	m = writerMetrics(metricsReg)
+	assert.Equal(12.0, m["dropped"])
+}
+
+// writerMetrics returns the writer metrics values by samples result, retries and queue.
+func writerMetrics(reg *prometheus.Registry) map[string]float64 {
+	res := map[string]float64{}
+	mfs, _ := reg.Gather()
+	for _, mf := range mfs {
+		switch mf.GetName() {
+		case "brigade_exporter_remote_write_samples_total":
+			for _, m := range mf.GetMetric() {
+				res[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+			}
+		case "brigade_exporter_remote_write_retries_total":
+			res["retries"] = mf.GetMetric()[0].GetCounter().GetValue()
+		case "brigade_exporter_remote_write_queue_batches":
+			res["queue"] = mf.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	return res
+}
@@ -6,6 +6,8 @@ import (
 
 	azurebrigade "github.com/Azure/brigade/pkg/brigade"
 	"github.com/Azure/brigade/pkg/storage"
+	"github.com/oklog/ulid"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/slok/brigade-exporter/pkg/log"
 )
@@ -52,12 +54,36 @@ func (b *brigade) GetProjects() ([]*Project, error) {
 			Repository: pr.Repo.Name,
 			Namespace:  pr.Kubernetes.Namespace,
 			Worker:     image,
+			Config: ProjectConfig{
+				AllowPrivilegedJobs: pr.AllowPrivilegedJobs,
+				AllowHostMounts:     pr.AllowHostMounts,
+				InitGitSubmodules:   pr.InitGitSubmodules,
+				DefaultScript:       pr.DefaultScript != "" || pr.DefaultScriptName != "",
+				Secrets:             len(pr.Secrets),
+				BuildStorageSize:    b.getStorageSize(pr),
+				BuildStorageClass:   pr.Kubernetes.BuildStorageClass,
+				CacheStorageClass:   pr.Kubernetes.CacheStorageClass,
+			},
 		}
 	}
 
 	return prs, nil
 }
 
+func (b *brigade) getStorageSize(pr *azurebrigade.Project) int64 {
+	if pr.Kubernetes.BuildStorageSize == "" {
+		return 0
+	}
+
+	q, err := resource.ParseQuantity(pr.Kubernetes.BuildStorageSize)
+	if err != nil {
+		b.logger.Warnf("invalid build storage size on project %s: %s", pr.ID, err)
+		return 0
+	}
+
+	return q.Value()
+}
+
 func (b *brigade) GetBuilds() ([]*Build, error) {
 	bblds, err := b.client.GetBuilds()
 	if err != nil {
@@ -74,6 +100,9 @@ func (b *brigade) GetBuilds() ([]*Build, error) {
 			Version:   bld.Revision.Commit,
 			Status:    b.getBuildStatus(bld),
 			Duration:  b.getBuildDuration(bld),
+			Creation:  b.getBuildCreation(bld),
+			Start:     b.getBuildStart(bld),
+			End:       b.getBuildEnd(bld),
 		}
 	}
 
@@ -108,6 +137,39 @@ func (b *brigade) getBuildDuration(bld *azurebrigade.Build) time.Duration {
 	return 0
 }
 
+// getBuildCreation returns the creation time of the build, the build IDs are
+// ULIDs so they have the creation time.
+func (b *brigade) getBuildCreation(bld *azurebrigade.Build) time.Time {
+	id, err := ulid.Parse(bld.ID)
+	if err != nil {
+		return time.Time{}
+	}
+
+	ms := int64(id.Time())
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+func (b *brigade) getBuildStart(bld *azurebrigade.Build) time.Time {
+	if bld.Worker == nil {
+		return time.Time{}
+	}
+
+	return bld.Worker.StartTime
+}
+
+func (b *brigade) getBuildEnd(bld *azurebrigade.Build) time.Time {
+	if bld.Worker == nil {
+		return time.Time{}
+	}
+
+	// Only get the end if build finished.
+	if bld.Worker.Status != azurebrigade.JobSucceeded && bld.Worker.Status != azurebrigade.JobFailed {
+		return time.Time{}
+	}
+
+	return bld.Worker.EndTime
+}
+
 func (b *brigade) GetJobs() ([]*Job, error) {
 	builds, err := b.client.GetBuilds()
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 var (
 	fakedBuildEventTypes = []string{"push", "pull_request", "deploy", "deploy_post_hook", "tag", "debug"}
 	fakedBuildProviders  = []string{"github", "docker", "gitlab", "brig", "toilet"}
+	fakedStorageClasses  = []string{"standard", "ssd", "nfs"}
 	fakedJobStatus       = []azurebrigade.JobStatus{azurebrigade.JobPending, azurebrigade.JobRunning, azurebrigade.JobSucceeded, azurebrigade.JobFailed, azurebrigade.JobUnknown}
 )
 
@@ -30,6 +31,16 @@ func (f *fake) GetProjects() ([]*Project, error) {
 			Repository: fmt.Sprintf("github.com/fake-exporter/project-%d", i),
 			Namespace:  fmt.Sprintf("ns%d", i),
 			Worker:     fmt.Sprintf("brigade-worker-%d", i),
+			Config: ProjectConfig{
+				AllowPrivilegedJobs: i%3 == 0,
+				AllowHostMounts:     i%5 == 0,
+				InitGitSubmodules:   i%2 == 0,
+				DefaultScript:       i%4 == 0,
+				Secrets:             i,
+				BuildStorageSize:    int64(i+1) * 50 * 1024 * 1024,
+				BuildStorageClass:   fakedStorageClasses[i%len(fakedStorageClasses)],
+				CacheStorageClass:   fakedStorageClasses[(i+1)%len(fakedStorageClasses)],
+			},
 		})
 	}
 	return prs, nil
@@ -47,6 +58,19 @@ func (f *fake) GetBuilds() ([]*Build, error) {
 		for j := 0; j < 10; j++ {
 			fakeIdentity := i + j
 			statusRand := statusSalt * int64(j*i)
+			status := fakedJobStatus[statusRand%int64(len(fakedJobStatus))]
+			duration := time.Duration((startID*fakeIdentity)%4000) * time.Second
+			start := time.Unix(int64(startID*600), 0).Add(time.Duration(fakeIdentity) * time.Minute)
+			creation := start.Add(-time.Duration(fakeIdentity) * time.Second)
+
+			// Only started builds have start and only finished builds have end.
+			var end time.Time
+			switch status {
+			case azurebrigade.JobPending:
+				start = time.Time{}
+			case azurebrigade.JobSucceeded, azurebrigade.JobFailed:
+				end = start.Add(duration)
+			}
 
 			blds = append(blds, &Build{
 				ID:        fmt.Sprintf("build-id-%d%d%d", startID, i, j),
@@ -54,8 +78,11 @@ func (f *fake) GetBuilds() ([]*Build, error) {
 				Type:      fakedBuildEventTypes[(22*startID*fakeIdentity)%len(fakedBuildEventTypes)],
 				Provider:  fakedBuildProviders[(23*startID*fakeIdentity)%len(fakedBuildProviders)],
 				Version:   fmt.Sprintf("%d", (1234567 * startID * fakeIdentity)),
-				Status:    fakedJobStatus[statusRand%int64(len(fakedJobStatus))].String(),
-				Duration:  time.Duration((startID*fakeIdentity)%4000) * time.Second,
+				Status:    status.String(),
+				Duration:  duration,
+				Creation:  creation,
+				Start:     start,
+				End:       end,
 			})
 
 		}
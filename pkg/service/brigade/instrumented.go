@@ -0,0 +1,98 @@
+package brigade
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	instrumentedNamespaceDef = "brigade"
+	instrumentedSubsystem    = "exporter_brigade"
+)
+
+// instrumented is a brigade.Interface implementation that measures the calls
+// to the wrapped brigade.Interface.
+type instrumented struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	objects         *prometheus.GaugeVec
+	next            Interface
+}
+
+// NewInstrumented returns a new brigade.Interface that measures the requests
+// and the returned objects of the wrapped brigade.Interface. The metrics have
+// the namespace, by default brigade.
+func NewInstrumented(namespace string, reg prometheus.Registerer, next Interface) (Interface, error) {
+	if namespace == "" {
+		namespace = instrumentedNamespaceDef
+	}
+
+	i := &instrumented{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: instrumentedSubsystem,
+			Name:      "requests_total",
+			Help:      "Number of requests made to Brigade.",
+		}, []string{"method", "success"}),
+
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: instrumentedSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the requests made to Brigade.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+
+		objects: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: instrumentedSubsystem,
+			Name:      "objects",
+			Help:      "Number of objects returned by the last successful request made to Brigade.",
+		}, []string{"method"}),
+
+		next: next,
+	}
+
+	for _, c := range []prometheus.Collector{i.requests, i.requestDuration, i.objects} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// GetProjects satisfies Interface.
+func (i *instrumented) GetProjects() (prjs []*Project, err error) {
+	defer i.observe("GetProjects", time.Now(), func() int { return len(prjs) }, &err)
+	return i.next.GetProjects()
+}
+
+// GetBuilds satisfies Interface.
+func (i *instrumented) GetBuilds() (blds []*Build, err error) {
+	defer i.observe("GetBuilds", time.Now(), func() int { return len(blds) }, &err)
+	return i.next.GetBuilds()
+}
+
+// GetJobs satisfies Interface.
+func (i *instrumented) GetJobs() (jobs []*Job, err error) {
+	defer i.observe("GetJobs", time.Now(), func() int { return len(jobs) }, &err)
+	return i.next.GetJobs()
+}
+
+// observe measures a request, it's meant to be deferred.
+func (i *instrumented) observe(method string, start time.Time, objects func() int, err *error) {
+	i.requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	success := *err == nil
+	if success {
+		i.objects.WithLabelValues(method).Set(float64(objects()))
+	}
+
+	successLabel := "true"
+	if !success {
+		successLabel = "false"
+	}
+	i.requests.WithLabelValues(method, successLabel).Inc()
+}
@@ -0,0 +1,126 @@
+package brigade_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+func TestInstrumented(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(svc brigade.Interface)
+		mock       func(m *mbrigade.Interface)
+		expMetrics []string
+	}{
+		{
+			name: "Getting projects should measure the request and the returned projects.",
+			call: func(svc brigade.Interface) {
+				svc.GetProjects()
+				svc.GetProjects()
+			},
+			mock: func(m *mbrigade.Interface) {
+				m.On("GetProjects").Return([]*brigade.Project{&brigade.Project{}, &brigade.Project{}}, nil)
+			},
+			expMetrics: []string{
+				`brigade_exporter_brigade_requests_total{method="GetProjects",success="true"} 2`,
+				`brigade_exporter_brigade_request_duration_seconds_count{method="GetProjects"} 2`,
+				`brigade_exporter_brigade_objects{method="GetProjects"} 2`,
+			},
+		},
+		{
+			name: "Getting builds should measure the request and the returned builds.",
+			call: func(svc brigade.Interface) {
+				svc.GetBuilds()
+			},
+			mock: func(m *mbrigade.Interface) {
+				m.On("GetBuilds").Return([]*brigade.Build{&brigade.Build{}, &brigade.Build{}, &brigade.Build{}}, nil)
+			},
+			expMetrics: []string{
+				`brigade_exporter_brigade_requests_total{method="GetBuilds",success="true"} 1`,
+				`brigade_exporter_brigade_request_duration_seconds_count{method="GetBuilds"} 1`,
+				`brigade_exporter_brigade_objects{method="GetBuilds"} 3`,
+			},
+		},
+		{
+			name: "Failed requests should be measured without returned objects.",
+			call: func(svc brigade.Interface) {
+				svc.GetJobs()
+			},
+			mock: func(m *mbrigade.Interface) {
+				m.On("GetJobs").Return(nil, errors.New("wanted error"))
+			},
+			expMetrics: []string{
+				`brigade_exporter_brigade_requests_total{method="GetJobs",success="false"} 1`,
+				`brigade_exporter_brigade_request_duration_seconds_count{method="GetJobs"} 1`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			test.mock(mbsvc)
+
+			// Create the instrumented service and make the calls.
+			promReg := prometheus.NewRegistry()
+			svc, err := brigade.NewInstrumented("", promReg, mbsvc)
+			if !assert.NoError(err) {
+				return
+			}
+			test.call(svc)
+
+			// Make request to ask for metrics.
+			h := promhttp.HandlerFor(promReg, promhttp.HandlerOpts{})
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			h.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			if assert.Equal(http.StatusOK, resp.StatusCode) {
+				body, _ := ioutil.ReadAll(resp.Body)
+				for _, expMetric := range test.expMetrics {
+					assert.Contains(string(body), expMetric, "metric not present on the result of metrics service")
+				}
+				assert.NotContains(string(body), `brigade_exporter_brigade_objects{method="GetJobs"}`)
+			}
+		})
+	}
+}
+
+func TestInstrumentedNamespace(t *testing.T) {
+	assert := assert.New(t)
+
+	mbsvc := &mbrigade.Interface{}
+	mbsvc.On("GetProjects").Return([]*brigade.Project{}, nil)
+
+	promReg := prometheus.NewRegistry()
+	svc, err := brigade.NewInstrumented("team_ci", promReg, mbsvc)
+	if !assert.NoError(err) {
+		return
+	}
+	svc.GetProjects()
+
+	mfs, err := promReg.Gather()
+	if assert.NoError(err) && assert.NotEmpty(mfs) {
+		for _, mf := range mfs {
+			assert.Contains(mf.GetName(), "team_ci_exporter_brigade_")
+		}
+	}
+
+	// The metrics can't be registered twice.
+	_, err = brigade.NewInstrumented("team_ci", promReg, mbsvc)
+	assert.Error(err)
+}
@@ -1,36 +1,79 @@
 package brigade
 
-import "time"
+import (
+	"time"
+
+	azurebrigade "github.com/Azure/brigade/pkg/brigade"
+)
+
+// Brigade build and job statuses.
+const (
+	StatusPending   = string(azurebrigade.JobPending)
+	StatusRunning   = string(azurebrigade.JobRunning)
+	StatusSucceeded = string(azurebrigade.JobSucceeded)
+	StatusFailed    = string(azurebrigade.JobFailed)
+	StatusUnknown   = string(azurebrigade.JobUnknown)
+)
 
 // Project is a representation of a brigade Project required by
 // the application.
 type Project struct {
-	ID         string
-	Name       string
-	Repository string
-	Namespace  string
-	Worker     string
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Repository string        `json:"repository"`
+	Namespace  string        `json:"namespace"`
+	Worker     string        `json:"worker"`
+	Config     ProjectConfig `json:"config"`
+}
+
+// ProjectConfig is the configuration of a brigade project.
+type ProjectConfig struct {
+	AllowPrivilegedJobs bool `json:"allow_privileged_jobs"`
+	AllowHostMounts     bool `json:"allow_host_mounts"`
+	InitGitSubmodules   bool `json:"init_git_submodules"`
+	DefaultScript       bool `json:"default_script"`
+	Secrets             int  `json:"secrets"`
+	// BuildStorageSize is the size of the build storage in bytes.
+	BuildStorageSize  int64  `json:"build_storage_size"`
+	BuildStorageClass string `json:"build_storage_class"`
+	CacheStorageClass string `json:"cache_storage_class"`
 }
 
-// Build is a representation of a brigade build required by the application.
+// Build is a representation of a brigade build required by the application. The
+// duration is not serialized as nanoseconds, the API serializes it in seconds.
 type Build struct {
-	ID        string
-	ProjectID string
-	Type      string
-	Provider  string
-	Version   string
-	Status    string
-	Duration  time.Duration
+	ID        string        `json:"id"`
+	ProjectID string        `json:"project_id"`
+	Type      string        `json:"type"`
+	Provider  string        `json:"provider"`
+	Version   string        `json:"version"`
+	Status    string        `json:"status"`
+	Duration  time.Duration `json:"-"`
+	// Creation is set even if the build has not started.
+	Creation time.Time `json:"creation"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// Created returns when the build was created, the builds without creation (e.g: the
+// builds of an old Brigade) use their start. The builds that have not started yet
+// are the newest ones, so the builds must be ordered by this instead of the start.
+func (b *Build) Created() time.Time {
+	if b.Creation.IsZero() {
+		return b.Start
+	}
+	return b.Creation
 }
 
-// Job is a representation of a brigade build job required by the application.
+// Job is a representation of a brigade build job required by the application. The
+// duration is not serialized as nanoseconds, the API serializes it in seconds.
 type Job struct {
-	ID       string
-	BuildID  string
-	Name     string
-	Image    string
-	Status   string
-	Duration time.Duration
-	Creation time.Time
-	Start    time.Time
+	ID       string        `json:"id"`
+	BuildID  string        `json:"build_id"`
+	Name     string        `json:"name"`
+	Image    string        `json:"image"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"-"`
+	Creation time.Time     `json:"creation"`
+	Start    time.Time     `json:"start"`
 }
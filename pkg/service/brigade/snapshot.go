@@ -0,0 +1,135 @@
+package brigade
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	snapshotProjects = "projects"
+	snapshotBuilds   = "builds"
+	snapshotJobs     = "jobs"
+)
+
+// Snapshot is a brigade.Interface that keeps the last results of the wrapped
+// brigade.Interface, so the state the collectors got from Brigade can be read
+// by other users (e.g: the API) without making more requests to Brigade.
+type Snapshot struct {
+	next   Interface
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	projects []*Project
+	builds   []*Build
+	jobs     []*Job
+	updated  map[string]time.Time
+
+	// refreshMu serializes the refreshes of the cached reads.
+	refreshMu sync.Mutex
+}
+
+// NewSnapshot returns a new Snapshot, the cached reads use the results until
+// they are older than the max age.
+func NewSnapshot(next Interface, maxAge time.Duration) *Snapshot {
+	return &Snapshot{
+		next:    next,
+		maxAge:  maxAge,
+		updated: map[string]time.Time{},
+	}
+}
+
+// GetProjects satisfies Interface, the projects are always got from Brigade.
+func (s *Snapshot) GetProjects() ([]*Project, error) {
+	prjs, err := s.next.GetProjects()
+	if err == nil {
+		s.mu.Lock()
+		s.projects = prjs
+		s.updated[snapshotProjects] = time.Now()
+		s.mu.Unlock()
+	}
+	return prjs, err
+}
+
+// GetBuilds satisfies Interface, the builds are always got from Brigade.
+func (s *Snapshot) GetBuilds() ([]*Build, error) {
+	blds, err := s.next.GetBuilds()
+	if err == nil {
+		s.mu.Lock()
+		s.builds = blds
+		s.updated[snapshotBuilds] = time.Now()
+		s.mu.Unlock()
+	}
+	return blds, err
+}
+
+// GetJobs satisfies Interface, the jobs are always got from Brigade.
+func (s *Snapshot) GetJobs() ([]*Job, error) {
+	jobs, err := s.next.GetJobs()
+	if err == nil {
+		s.mu.Lock()
+		s.jobs = jobs
+		s.updated[snapshotJobs] = time.Now()
+		s.mu.Unlock()
+	}
+	return jobs, err
+}
+
+// fresh returns if the results are newer than the max age, it needs the lock.
+func (s *Snapshot) fresh(kind string) bool {
+	updated, ok := s.updated[kind]
+	return ok && time.Since(updated) < s.maxAge
+}
+
+// Cached returns a brigade.Interface that reads the last results while they are
+// newer than the max age, otherwise they are got again from Brigade. The results
+// are shared so they must not be modified.
+func (s *Snapshot) Cached() Interface {
+	return cachedSnapshot{s: s}
+}
+
+// cachedSnapshot is the brigade.Interface of the cached reads of a Snapshot.
+type cachedSnapshot struct {
+	s *Snapshot
+}
+
+// GetProjects satisfies Interface.
+func (c cachedSnapshot) GetProjects() ([]*Project, error) {
+	c.s.refreshMu.Lock()
+	defer c.s.refreshMu.Unlock()
+
+	c.s.mu.Lock()
+	prjs, fresh := c.s.projects, c.s.fresh(snapshotProjects)
+	c.s.mu.Unlock()
+	if fresh {
+		return prjs, nil
+	}
+	return c.s.GetProjects()
+}
+
+// GetBuilds satisfies Interface.
+func (c cachedSnapshot) GetBuilds() ([]*Build, error) {
+	c.s.refreshMu.Lock()
+	defer c.s.refreshMu.Unlock()
+
+	c.s.mu.Lock()
+	blds, fresh := c.s.builds, c.s.fresh(snapshotBuilds)
+	c.s.mu.Unlock()
+	if fresh {
+		return blds, nil
+	}
+	return c.s.GetBuilds()
+}
+
+// GetJobs satisfies Interface.
+func (c cachedSnapshot) GetJobs() ([]*Job, error) {
+	c.s.refreshMu.Lock()
+	defer c.s.refreshMu.Unlock()
+
+	c.s.mu.Lock()
+	jobs, fresh := c.s.jobs, c.s.fresh(snapshotJobs)
+	c.s.mu.Unlock()
+	if fresh {
+		return jobs, nil
+	}
+	return c.s.GetJobs()
+}
@@ -0,0 +1,88 @@
+package brigade_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+func TestSnapshot(t *testing.T) {
+	blds1 := []*brigade.Build{&brigade.Build{ID: "bld1"}}
+	blds2 := []*brigade.Build{&brigade.Build{ID: "bld1"}, &brigade.Build{ID: "bld2"}}
+
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		collect   bool
+		err       error
+		expBuilds []*brigade.Build
+		expCalls  int
+	}{
+		{
+			name:      "The cached reads should use the results of the collections.",
+			maxAge:    time.Hour,
+			collect:   true,
+			expBuilds: blds1,
+			expCalls:  1,
+		},
+		{
+			name:      "The cached reads without results should get them from Brigade.",
+			maxAge:    time.Hour,
+			expBuilds: blds1,
+			expCalls:  1,
+		},
+		{
+			name:      "The cached reads with old results should get them from Brigade.",
+			collect:   true,
+			expBuilds: blds2,
+			expCalls:  2,
+		},
+		{
+			name:     "The failed results shouldn't be kept.",
+			maxAge:   time.Hour,
+			collect:  true,
+			err:      errors.New("wanted error"),
+			expCalls: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mbsvc := &mbrigade.Interface{}
+			if test.err != nil {
+				mbsvc.On("GetBuilds").Return(nil, test.err)
+			} else {
+				mbsvc.On("GetBuilds").Once().Return(blds1, nil)
+				mbsvc.On("GetBuilds").Once().Return(blds2, nil)
+			}
+			s := brigade.NewSnapshot(mbsvc, test.maxAge)
+
+			if test.collect {
+				s.GetBuilds()
+			}
+
+			// Read multiple times, only the first one can get the builds from Brigade.
+			cached := s.Cached()
+			for i := 0; i < 2; i++ {
+				blds, err := cached.GetBuilds()
+				if test.err != nil {
+					assert.Error(err)
+				} else if assert.NoError(err) {
+					assert.Equal(test.expBuilds, blds)
+				}
+				if test.maxAge == 0 {
+					break
+				}
+			}
+
+			mbsvc.AssertNumberOfCalls(t, "GetBuilds", test.expCalls)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package status
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+)
+
+const stuckThresholdDef = time.Hour
+
+// SubcollectorStatuser returns the status of the last collection of the subcollectors.
+type SubcollectorStatuser interface {
+	SubcollectorStatuses() []collector.SubcollectorStatus
+}
+
+// Config is the status page configuration.
+type Config struct {
+	// MetricsPath is the path of the metrics linked from the page.
+	MetricsPath string
+	// StuckThreshold is the time after a running or pending build is considered stuck.
+	StuckThreshold time.Duration
+}
+
+// defaults sets the required defaults.
+func (c *Config) defaults() {
+	if c.StuckThreshold == 0 {
+		c.StuckThreshold = stuckThresholdDef
+	}
+}
+
+// projectRow is a project with its last build.
+type projectRow struct {
+	Project   *brigade.Project
+	LastBuild *brigade.Build
+}
+
+// buildRow is a build with the name of its project.
+type buildRow struct {
+	Build   *brigade.Build
+	Project string
+	Stuck   bool
+	// Since is when the build started, or when it was created if it
+	// has not started yet (e.g: pending builds).
+	Since time.Time
+}
+
+// pageData is the data rendered on the status page.
+type pageData struct {
+	MetricsPath    string
+	StuckThreshold time.Duration
+	Now            time.Time
+	Error          string
+	Collectors     []collector.SubcollectorStatus
+	Projects       []projectRow
+	Running        []buildRow
+	Stuck          []buildRow
+}
+
+// handler serves the status page.
+type handler struct {
+	cfg        Config
+	brigadeSVC brigade.Interface
+	exporter   SubcollectorStatuser
+	logger     log.Logger
+}
+
+// NewHandler returns a new handler that renders an HTML status page with the projects
+// and their last builds, the running and stuck builds and the health of the collectors.
+func NewHandler(cfg Config, brigadeSVC brigade.Interface, exporter SubcollectorStatuser, logger log.Logger) http.Handler {
+	cfg.defaults()
+
+	return &handler{
+		cfg:        cfg,
+		brigadeSVC: brigadeSVC,
+		exporter:   exporter,
+		logger:     logger,
+	}
+}
+
+// ServeHTTP satisfies http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The page is served on the root so we don't serve any other path.
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := pageData{
+		MetricsPath:    h.cfg.MetricsPath,
+		StuckThreshold: h.cfg.StuckThreshold,
+		Now:            time.Now(),
+		Collectors:     h.exporter.SubcollectorStatuses(),
+	}
+
+	// If Brigade is not reachable the page still shows the collectors health.
+	if err := h.fillBrigade(&data); err != nil {
+		h.logger.Errorf("error getting brigade state for the status page: %s", err)
+		data.Error = err.Error()
+	}
+
+	var b bytes.Buffer
+	if err := pageTpl.Execute(&b, data); err != nil {
+		h.logger.Errorf("error rendering status page: %s", err)
+		http.Error(w, "error rendering status page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	b.WriteTo(w)
+}
+
+// fillBrigade fills the page with the state of Brigade.
+func (h *handler) fillBrigade(data *pageData) error {
+	prjs, err := h.brigadeSVC.GetProjects()
+	if err != nil {
+		return err
+	}
+
+	blds, err := h.brigadeSVC.GetBuilds()
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	for _, p := range prjs {
+		names[p.ID] = p.Name
+	}
+
+	lastBlds := map[string]*brigade.Build{}
+	for _, b := range blds {
+		if last, ok := lastBlds[b.ProjectID]; !ok || b.Created().After(last.Created()) {
+			lastBlds[b.ProjectID] = b
+		}
+
+		since := b.Start
+		if since.IsZero() {
+			since = b.Creation
+		}
+
+		active := b.Status == brigade.StatusRunning || b.Status == brigade.StatusPending
+		stuck := active && !since.IsZero() && data.Now.Sub(since) > h.cfg.StuckThreshold
+		row := buildRow{Build: b, Project: names[b.ProjectID], Stuck: stuck, Since: since}
+		if b.Status == brigade.StatusRunning {
+			data.Running = append(data.Running, row)
+		}
+		if stuck {
+			data.Stuck = append(data.Stuck, row)
+		}
+	}
+
+	for _, p := range prjs {
+		data.Projects = append(data.Projects, projectRow{Project: p, LastBuild: lastBlds[p.ID]})
+	}
+
+	sort.SliceStable(data.Projects, func(i, j int) bool { return data.Projects[i].Project.Name < data.Projects[j].Project.Name })
+	sortBuilds(data.Running)
+	sortBuilds(data.Stuck)
+
+	return nil
+}
+
+// sortBuilds sorts the builds by the oldest first.
+func sortBuilds(rows []buildRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if !rows[i].Since.Equal(rows[j].Since) {
+			return rows[i].Since.Before(rows[j].Since)
+		}
+		return rows[i].Build.ID < rows[j].Build.ID
+	})
+}
+
+// ago returns the time passed since t rounded to seconds.
+func ago(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return now.Sub(t).Round(time.Second).String() + " ago"
+}
+
+// round returns the duration rounded to milliseconds.
+func round(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
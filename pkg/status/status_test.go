@@ -0,0 +1,138 @@
+package status_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	mbrigade "github.com/slok/brigade-exporter/mocks/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/collector"
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/service/brigade"
+	"github.com/slok/brigade-exporter/pkg/status"
+)
+
+// statuses is a fixed SubcollectorStatuser.
+type statuses []collector.SubcollectorStatus
+
+func (s statuses) SubcollectorStatuses() []collector.SubcollectorStatus { return s }
+
+func TestStatusPage(t *testing.T) {
+	now := time.Now()
+
+	testProjects := []*brigade.Project{
+		&brigade.Project{ID: "prj1", Name: "team/app1", Repository: "github.com/team/app1"},
+		&brigade.Project{ID: "prj2", Name: "team/app2", Repository: "github.com/team/app2"},
+		&brigade.Project{ID: "prj3", Name: "team/<script>", Repository: "github.com/team/app3"},
+	}
+	testBuilds := []*brigade.Build{
+		&brigade.Build{ID: "bld1", ProjectID: "prj1", Type: "push", Status: "Succeeded", Start: now.Add(-3 * time.Hour)},
+		&brigade.Build{ID: "bld2", ProjectID: "prj1", Type: "push", Version: "1234567890", Status: "Running", Start: now.Add(-10 * time.Minute)},
+		&brigade.Build{ID: "bld3", ProjectID: "prj2", Type: "deploy", Version: "1234567891", Status: "Running", Start: now.Add(-2 * time.Hour)},
+		&brigade.Build{ID: "bld4", ProjectID: "prj2", Type: "deploy", Status: "Pending", Start: now.Add(-5 * time.Hour)},
+		&brigade.Build{ID: "bld5", ProjectID: "prj1", Type: "push", Status: "Pending", Creation: now.Add(-4 * time.Hour)},
+		&brigade.Build{ID: "bld6", ProjectID: "prj1", Type: "push", Status: "Pending", Creation: now.Add(-5 * time.Minute)},
+	}
+	testStatuses := statuses{
+		{Name: "builds", LastCollection: now.Add(-5 * time.Second), Duration: 120 * time.Millisecond, Success: true},
+		{Name: "jobs", LastCollection: now.Add(-5 * time.Second), Duration: 10 * time.Second, Error: "context deadline exceeded"},
+		{Name: "projects"},
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		projectsErr error
+		expCode     int
+		expContains []string
+		notContains []string
+	}{
+		{
+			name:    "The status page should render the state of Brigade and the collectors.",
+			path:    "/",
+			expCode: http.StatusOK,
+			expContains: []string{
+				`<a href="/metrics">Metrics</a>`,
+
+				// Collectors.
+				"<td>builds</td>\n<td>5s ago</td>\n<td>120ms</td>\n<td><span class=\"ok\">ok</span></td>",
+				"<td>jobs</td>\n<td>5s ago</td>\n<td>10s</td>\n<td><span class=\"error\">failed</span></td>\n<td>context deadline exceeded</td>",
+				"<td>projects</td>\n<td>never</td>",
+
+				// Stuck builds.
+				"Running or pending builds started (or created if not started) more than 1h0m0s ago.",
+				"<tr class=\"stuck\">\n<td>bld4</td>\n<td>team/app2</td>\n<td>deploy</td>\n<td>Pending</td>\n<td>5h0m0s ago</td>",
+				// Pending builds that have not started are stuck since their creation.
+				"<tr class=\"stuck\">\n<td>bld5</td>\n<td>team/app1</td>\n<td>push</td>\n<td>Pending</td>\n<td>4h0m0s ago</td>",
+				"<tr class=\"stuck\">\n<td>bld3</td>\n<td>team/app2</td>\n<td>deploy</td>\n<td>Running</td>\n<td>2h0m0s ago</td>",
+
+				// Running builds.
+				"<tr class=\"stuck\">\n<td>bld3</td>\n<td>team/app2</td>\n<td>deploy</td>\n<td>1234567891</td>",
+				"<tr>\n<td>bld2</td>\n<td>team/app1</td>\n<td>push</td>\n<td>1234567890</td>\n<td>10m0s ago</td>",
+
+				// Projects with the last build.
+				// The last build is the newest created, even if it has not started.
+				"<td>team/app1</td>\n<td>github.com/team/app1</td>\n<td>bld6</td>\n<td class=\"Pending\">Pending</td>",
+				"<td>team/app2</td>\n<td>github.com/team/app2</td>\n<td>bld3</td>\n<td class=\"Running\">Running</td>",
+				"<td>team/&lt;script&gt;</td>\n<td>github.com/team/app3</td>\n<td colspan=\"3\">No builds</td>",
+			},
+			notContains: []string{
+				"<td>bld1</td>",
+				"<tr class=\"stuck\">\n<td>bld6</td>",
+				"<script>",
+				"Error getting the Brigade state",
+			},
+		},
+		{
+			name:        "If Brigade is not reachable the status page should render the error and the collectors.",
+			path:        "/",
+			projectsErr: fmt.Errorf("wanted error"),
+			expCode:     http.StatusOK,
+			expContains: []string{
+				`<p class="error">Error getting the Brigade state: wanted error</p>`,
+				"<td>builds</td>",
+				"<p>No stuck builds.</p>",
+				"<p>No running builds.</p>",
+			},
+		},
+		{
+			name:    "Other paths should not be found.",
+			path:    "/missing",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mbsvc := &mbrigade.Interface{}
+			if test.projectsErr != nil {
+				mbsvc.On("GetProjects").Return(nil, test.projectsErr)
+			} else {
+				mbsvc.On("GetProjects").Return(testProjects, nil)
+			}
+			mbsvc.On("GetBuilds").Return(testBuilds, nil)
+
+			h := status.NewHandler(status.Config{MetricsPath: "/metrics"}, mbsvc, testStatuses, log.Dummy)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", test.path, nil)
+			h.ServeHTTP(rec, req)
+
+			if assert.Equal(test.expCode, rec.Code) {
+				body := rec.Body.String()
+				for _, exp := range test.expContains {
+					assert.Contains(body, exp)
+				}
+				for _, notExp := range test.notContains {
+					assert.NotContains(body, notExp)
+				}
+			}
+		})
+	}
+}
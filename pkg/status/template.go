@@ -0,0 +1,100 @@
+package status
+
+import (
+	"html/template"
+)
+
+var pageTpl = template.Must(template.New("status").Funcs(template.FuncMap{
+	"ago":   ago,
+	"round": round,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Brigade Exporter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+.error, .Failed, .stuck { color: #c00; }
+.Succeeded, .ok { color: #080; }
+.Running, .Pending { color: #c70; }
+</style>
+</head>
+<body>
+<h1>Brigade Exporter</h1>
+<p><a href="{{ .MetricsPath }}">Metrics</a></p>
+{{- if .Error }}
+<p class="error">Error getting the Brigade state: {{ .Error }}</p>
+{{- end }}
+
+<h2>Collectors</h2>
+<table>
+<tr><th>Collector</th><th>Last collection</th><th>Duration</th><th>Status</th><th>Error</th></tr>
+{{- range .Collectors }}
+<tr>
+<td>{{ .Name }}</td>
+<td>{{ ago $.Now .LastCollection }}</td>
+<td>{{ if not .LastCollection.IsZero }}{{ round .Duration }}{{ end }}</td>
+<td>{{ if .LastCollection.IsZero }}-{{ else if .Success }}<span class="ok">ok</span>{{ else }}<span class="error">failed</span>{{ end }}</td>
+<td>{{ .Error }}</td>
+</tr>
+{{- end }}
+</table>
+
+<h2>Stuck builds</h2>
+<p>Running or pending builds started (or created if not started) more than {{ .StuckThreshold }} ago.</p>
+{{- if .Stuck }}
+<table>
+<tr><th>Build</th><th>Project</th><th>Event</th><th>Status</th><th>Started</th></tr>
+{{- range .Stuck }}
+<tr class="stuck">
+<td>{{ .Build.ID }}</td>
+<td>{{ .Project }}</td>
+<td>{{ .Build.Type }}</td>
+<td>{{ .Build.Status }}</td>
+<td>{{ ago $.Now .Since }}</td>
+</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No stuck builds.</p>
+{{- end }}
+
+<h2>Running builds</h2>
+{{- if .Running }}
+<table>
+<tr><th>Build</th><th>Project</th><th>Event</th><th>Version</th><th>Started</th></tr>
+{{- range .Running }}
+<tr{{ if .Stuck }} class="stuck"{{ end }}>
+<td>{{ .Build.ID }}</td>
+<td>{{ .Project }}</td>
+<td>{{ .Build.Type }}</td>
+<td>{{ .Build.Version }}</td>
+<td>{{ ago $.Now .Build.Start }}</td>
+</tr>
+{{- end }}
+</table>
+{{- else }}
+<p>No running builds.</p>
+{{- end }}
+
+<h2>Projects</h2>
+<table>
+<tr><th>Project</th><th>Repository</th><th>Last build</th><th>Status</th><th>Started</th></tr>
+{{- range .Projects }}
+<tr>
+<td>{{ .Project.Name }}</td>
+<td>{{ .Project.Repository }}</td>
+{{- with .LastBuild }}
+<td>{{ .ID }}</td>
+<td class="{{ .Status }}">{{ .Status }}</td>
+<td>{{ ago $.Now .Start }}</td>
+{{- else }}
+<td colspan="3">No builds</td>
+{{- end }}
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,68 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const bearerPrefix = "Bearer "
+
+// NewAuthHandler returns a handler that only lets through the requests authenticated
+// with one of the basic auth users or bearer tokens of the configuration. If
+// the configuration doesn't have authentication all the requests are allowed.
+func NewAuthHandler(cfg Config, next http.Handler) http.Handler {
+	if !cfg.AuthEnabled() {
+		return next
+	}
+
+	return &authHandler{
+		users:  cfg.BasicAuthUsers,
+		tokens: cfg.BearerTokens,
+		next:   next,
+	}
+}
+
+type authHandler struct {
+	users  map[string]string
+	tokens []string
+	next   http.Handler
+}
+
+// ServeHTTP satisfies http.Handler.
+func (a *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.authenticated(r) {
+		a.next.ServeHTTP(w, r)
+		return
+	}
+
+	if len(a.users) > 0 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="brigade-exporter"`)
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// authenticated returns if the request has valid credentials.
+func (a *authHandler) authenticated(r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		hash, ok := a.users[user]
+		return ok && bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+	}
+
+	authz := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authz, bearerPrefix) {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(authz, bearerPrefix))
+
+	// Check all the tokens so the time doesn't tell what token matched.
+	valid := false
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), token) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,110 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/slok/brigade-exporter/pkg/web"
+)
+
+func TestAuthHandler(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cr3t"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		cfg       web.Config
+		request   func(r *http.Request)
+		expCode   int
+		expHeader string
+	}{
+		{
+			name:    "Without authentication configured the requests should be allowed.",
+			cfg:     web.Config{},
+			request: func(r *http.Request) {},
+			expCode: http.StatusOK,
+		},
+		{
+			name:      "Requests without credentials should be rejected.",
+			cfg:       web.Config{BasicAuthUsers: map[string]string{"prometheus": string(hash)}},
+			request:   func(r *http.Request) {},
+			expCode:   http.StatusUnauthorized,
+			expHeader: `Basic realm="brigade-exporter"`,
+		},
+		{
+			name: "Requests with a valid basic auth user should be allowed.",
+			cfg:  web.Config{BasicAuthUsers: map[string]string{"prometheus": string(hash)}},
+			request: func(r *http.Request) {
+				r.SetBasicAuth("prometheus", "s3cr3t")
+			},
+			expCode: http.StatusOK,
+		},
+		{
+			name: "Requests with an invalid basic auth password should be rejected.",
+			cfg:  web.Config{BasicAuthUsers: map[string]string{"prometheus": string(hash)}},
+			request: func(r *http.Request) {
+				r.SetBasicAuth("prometheus", "wrong")
+			},
+			expCode:   http.StatusUnauthorized,
+			expHeader: `Basic realm="brigade-exporter"`,
+		},
+		{
+			name: "Requests with an unknown basic auth user should be rejected.",
+			cfg:  web.Config{BasicAuthUsers: map[string]string{"prometheus": string(hash)}},
+			request: func(r *http.Request) {
+				r.SetBasicAuth("grafana", "s3cr3t")
+			},
+			expCode:   http.StatusUnauthorized,
+			expHeader: `Basic realm="brigade-exporter"`,
+		},
+		{
+			name: "Requests with a valid bearer token should be allowed.",
+			cfg:  web.Config{BearerTokens: []string{"token1", "token2"}},
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token2")
+			},
+			expCode: http.StatusOK,
+		},
+		{
+			name: "Requests with an invalid bearer token should be rejected.",
+			cfg:  web.Config{BearerTokens: []string{"token1", "token2"}},
+			request: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer token3")
+			},
+			expCode: http.StatusUnauthorized,
+		},
+		{
+			name: "Bearer tokens shouldn't be accepted as basic auth passwords.",
+			cfg:  web.Config{BearerTokens: []string{"token1"}},
+			request: func(r *http.Request) {
+				r.SetBasicAuth("token1", "token1")
+			},
+			expCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+			h := web.NewAuthHandler(test.cfg, next)
+
+			r := httptest.NewRequest("GET", "/metrics", nil)
+			test.request(r)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(test.expCode, w.Code)
+			assert.Equal(test.expHeader, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
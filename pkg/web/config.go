@@ -0,0 +1,115 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Client authentication types of the TLS server.
+const (
+	NoClientCert               = "NoClientCert"
+	RequestClientCert          = "RequestClientCert"
+	RequireAnyClientCert       = "RequireAnyClientCert"
+	VerifyClientCertIfGiven    = "VerifyClientCertIfGiven"
+	RequireAndVerifyClientCert = "RequireAndVerifyClientCert"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	NoClientCert:               tls.NoClientCert,
+	RequestClientCert:          tls.RequestClientCert,
+	RequireAnyClientCert:       tls.RequireAnyClientCert,
+	VerifyClientCertIfGiven:    tls.VerifyClientCertIfGiven,
+	RequireAndVerifyClientCert: tls.RequireAndVerifyClientCert,
+}
+
+// Config is the configuration of the web server, it follows the
+// format of the Prometheus exporter-toolkit web configuration file.
+type Config struct {
+	// TLSServerConfig is the TLS configuration of the server.
+	TLSServerConfig TLSServerConfig `yaml:"tls_server_config"`
+	// BasicAuthUsers are the users allowed to access using basic auth, the key is the
+	// user and the value the bcrypt hash of the password.
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+	// BearerTokens are the tokens allowed to access using the bearer token authorization.
+	BearerTokens []string `yaml:"bearer_tokens"`
+}
+
+// TLSServerConfig is the TLS configuration of the server.
+type TLSServerConfig struct {
+	// CertFile is the path of the server certificate, if set TLS will be enabled.
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path of the server certificate key.
+	KeyFile string `yaml:"key_file"`
+	// ClientAuthType is the policy of the server for the client certificates, by
+	// default RequireAndVerifyClientCert if a client CA is set, NoClientCert otherwise.
+	ClientAuthType string `yaml:"client_auth_type"`
+	// ClientCAFile is the path of the CA used to verify the client certificates.
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// LoadConfig loads the web configuration from a YAML file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("could not read web configuration file: %s", err)
+	}
+
+	if err := yaml.UnmarshalStrict(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse web configuration file: %s", err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate will check the web configuration is valid.
+func (c Config) Validate() error {
+	tc := c.TLSServerConfig
+	if (tc.CertFile == "") != (tc.KeyFile == "") {
+		return fmt.Errorf("both the TLS certificate and key files are required")
+	}
+
+	if !c.TLSEnabled() && (tc.ClientCAFile != "" || tc.ClientAuthType != "") {
+		return fmt.Errorf("client certificates require TLS to be enabled")
+	}
+
+	if tc.ClientAuthType != "" {
+		if _, ok := clientAuthTypes[tc.ClientAuthType]; !ok {
+			return fmt.Errorf("invalid client auth type %q", tc.ClientAuthType)
+		}
+	}
+
+	// Without a CA all the client certificates would fail the verification.
+	verify := tc.ClientAuthType == RequireAndVerifyClientCert || tc.ClientAuthType == VerifyClientCertIfGiven
+	if verify && tc.ClientCAFile == "" {
+		return fmt.Errorf("client auth type %s requires a client CA file", tc.ClientAuthType)
+	}
+
+	for user, hash := range c.BasicAuthUsers {
+		if user == "" || hash == "" {
+			return fmt.Errorf("basic auth users require a user and a password hash")
+		}
+	}
+
+	for _, token := range c.BearerTokens {
+		if token == "" {
+			return fmt.Errorf("bearer tokens can't be empty")
+		}
+	}
+
+	return nil
+}
+
+// TLSEnabled returns if the server needs to serve using TLS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSServerConfig.CertFile != ""
+}
+
+// AuthEnabled returns if the server requires authentication.
+func (c Config) AuthEnabled() bool {
+	return len(c.BasicAuthUsers) > 0 || len(c.BearerTokens) > 0
+}
@@ -0,0 +1,99 @@
+package web_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/web"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		expCfg web.Config
+		expErr bool
+	}{
+		{
+			name: "A complete configuration should be loaded.",
+			config: `
+tls_server_config:
+  cert_file: /etc/tls/tls.crt
+  key_file: /etc/tls/tls.key
+  client_auth_type: VerifyClientCertIfGiven
+  client_ca_file: /etc/tls/ca.crt
+basic_auth_users:
+  prometheus: $2y$10$abcdefghijklmnopqrstuv
+bearer_tokens:
+  - token1
+`,
+			expCfg: web.Config{
+				TLSServerConfig: web.TLSServerConfig{
+					CertFile:       "/etc/tls/tls.crt",
+					KeyFile:        "/etc/tls/tls.key",
+					ClientAuthType: "VerifyClientCertIfGiven",
+					ClientCAFile:   "/etc/tls/ca.crt",
+				},
+				BasicAuthUsers: map[string]string{"prometheus": "$2y$10$abcdefghijklmnopqrstuv"},
+				BearerTokens:   []string{"token1"},
+			},
+		},
+		{
+			name:   "Unknown fields should error.",
+			config: "tls_config:\n  cert_file: /etc/tls/tls.crt\n",
+			expErr: true,
+		},
+		{
+			name:   "A certificate without key should error.",
+			config: "tls_server_config:\n  cert_file: /etc/tls/tls.crt\n",
+			expErr: true,
+		},
+		{
+			name:   "A client CA without TLS should error.",
+			config: "tls_server_config:\n  client_ca_file: /etc/tls/ca.crt\n",
+			expErr: true,
+		},
+		{
+			name:   "An invalid client auth type should error.",
+			config: "tls_server_config:\n  cert_file: /etc/tls/tls.crt\n  key_file: /etc/tls/tls.key\n  client_auth_type: Always\n",
+			expErr: true,
+		},
+		{
+			name:   "Verifying the client certificates without client CA should error.",
+			config: "tls_server_config:\n  cert_file: /etc/tls/tls.crt\n  key_file: /etc/tls/tls.key\n  client_auth_type: RequireAndVerifyClientCert\n",
+			expErr: true,
+		},
+		{
+			name:   "An empty bearer token should error.",
+			config: "bearer_tokens:\n  - \"\"\n",
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			dir, err := ioutil.TempDir("", "web-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "web.yml")
+			if err := ioutil.WriteFile(path, []byte(test.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := web.LoadConfig(path)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expCfg, cfg)
+			}
+		})
+	}
+}
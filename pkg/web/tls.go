@@ -0,0 +1,125 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+)
+
+// NewTLSConfig returns the TLS configuration of the server. The certificate and key
+// files are checked on every handshake and reloaded when they change, so the
+// certificates can be rotated without restarting the server.
+func NewTLSConfig(cfg TLSServerConfig, logger log.Logger) (*tls.Config, error) {
+	r := &certReloader{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		logger:   logger,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		b, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("could not load any certificate from the client CA file")
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if cfg.ClientAuthType != "" {
+		tc.ClientAuth = clientAuthTypes[cfg.ClientAuthType]
+	}
+
+	return tc, nil
+}
+
+// certReloader loads the certificate again when the certificate or key
+// files change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   log.Logger
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// modTimes returns the modification time of the certificate and key files.
+func (c *certReloader) modTimes() (cert, key time.Time, err error) {
+	cs, err := os.Stat(c.certFile)
+	if err != nil {
+		return cert, key, err
+	}
+	ks, err := os.Stat(c.keyFile)
+	if err != nil {
+		return cert, key, err
+	}
+	return cs.ModTime(), ks.ModTime(), nil
+}
+
+// reload loads the certificate and key files.
+func (c *certReloader) reload() error {
+	certModTime, keyModTime, err := c.modTimes()
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate: %s", err)
+	}
+
+	// Record the modification times even if the load fails, otherwise every handshake
+	// would try to reload the broken files until they change again.
+	c.mu.Lock()
+	c.certModTime = certModTime
+	c.keyModTime = keyModTime
+	c.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate: %s", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+// getCertificate satisfies tls.Config GetCertificate, it returns the last loaded
+// certificate reloading it first if the files changed. If the reload fails the
+// previous certificate is kept until the files change again.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certModTime, keyModTime, err := c.modTimes()
+
+	c.mu.Lock()
+	changed := err == nil && (!certModTime.Equal(c.certModTime) || !keyModTime.Equal(c.keyModTime))
+	c.mu.Unlock()
+
+	if changed {
+		if err := c.reload(); err != nil {
+			c.logger.Errorf("error reloading the TLS certificate, using the previous one: %s", err)
+		} else {
+			c.logger.Infof("TLS certificate reloaded")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cert, nil
+}
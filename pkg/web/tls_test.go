@@ -0,0 +1,180 @@
+package web_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/brigade-exporter/pkg/log"
+	"github.com/slok/brigade-exporter/pkg/web"
+)
+
+// writeCert writes a new self signed certificate and its key with the
+// common name and modification time.
+func writeCert(t *testing.T, certFile, keyFile, cn string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	for file, data := range map[string][]byte{certFile: certPEM, keyFile: keyPEM} {
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Subject.CommonName
+}
+
+func TestTLSConfigReload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "web-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	now := time.Now()
+	writeCert(t, certFile, keyFile, "first", now.Add(-time.Minute))
+
+	tc, err := web.NewTLSConfig(web.TLSServerConfig{CertFile: certFile, KeyFile: keyFile}, log.Dummy)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(tls.NoClientCert, tc.ClientAuth)
+
+	cert, err := tc.GetCertificate(nil)
+	if assert.NoError(err) {
+		assert.Equal("first", commonName(t, cert))
+	}
+
+	// Rotate the certificate, it should be reloaded.
+	writeCert(t, certFile, keyFile, "second", now)
+	cert, err = tc.GetCertificate(nil)
+	if assert.NoError(err) {
+		assert.Equal("second", commonName(t, cert))
+	}
+
+	// A broken certificate should keep the previous one.
+	if err := ioutil.WriteFile(certFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(certFile, now.Add(time.Minute), now.Add(time.Minute))
+	cert, err = tc.GetCertificate(nil)
+	if assert.NoError(err) {
+		assert.Equal("second", commonName(t, cert))
+	}
+}
+
+// errorCounter is a logger that counts the logged errors.
+type errorCounter struct {
+	log.DummyLogger
+	errors int
+}
+
+func (e *errorCounter) Errorf(string, ...interface{}) { e.errors++ }
+
+func TestTLSConfigReloadFailedOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "web-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	now := time.Now()
+	writeCert(t, certFile, keyFile, "first", now.Add(-time.Minute))
+
+	logger := &errorCounter{}
+	tc, err := web.NewTLSConfig(web.TLSServerConfig{CertFile: certFile, KeyFile: keyFile}, logger)
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Rotate only the certificate, the key doesn't match until it's rotated too.
+	writeCert(t, certFile, filepath.Join(dir, "new.key"), "second", now)
+	for i := 0; i < 3; i++ {
+		cert, err := tc.GetCertificate(nil)
+		if assert.NoError(err) {
+			assert.Equal("first", commonName(t, cert))
+		}
+	}
+	assert.Equal(1, logger.errors, "the broken files should be reloaded only once")
+
+	// Once the key is rotated it should be reloaded.
+	if err := os.Rename(filepath.Join(dir, "new.key"), keyFile); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(keyFile, now.Add(time.Minute), now.Add(time.Minute))
+	cert, err := tc.GetCertificate(nil)
+	if assert.NoError(err) {
+		assert.Equal("second", commonName(t, cert))
+	}
+	assert.Equal(1, logger.errors)
+}
+
+func TestTLSConfigClientCA(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "web-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "server", time.Now())
+
+	// The self signed certificate is used as the client CA.
+	tc, err := web.NewTLSConfig(web.TLSServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}, log.Dummy)
+	if assert.NoError(err) {
+		assert.Equal(tls.RequireAndVerifyClientCert, tc.ClientAuth)
+		assert.NotNil(tc.ClientCAs)
+	}
+
+	tc, err = web.NewTLSConfig(web.TLSServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, ClientAuthType: web.VerifyClientCertIfGiven}, log.Dummy)
+	if assert.NoError(err) {
+		assert.Equal(tls.VerifyClientCertIfGiven, tc.ClientAuth)
+	}
+
+	_, err = web.NewTLSConfig(web.TLSServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, log.Dummy)
+	assert.Error(err)
+}